@@ -0,0 +1,132 @@
+package vial
+
+import (
+	"fmt"
+	"log"
+)
+
+// NewChild creates a container which resolves the structs and the interfaces missing in it from the container.
+// The child has its own singletons, and copies the property sources, the profiles and the scopes of the container.
+func (c *Container) NewChild() *Container {
+	child := newContainer()
+	child.parent = c
+	c.attach(child)
+	child.properties = append(propertySources{}, c.properties...)
+	child.profiles = append([]string{}, c.profiles...)
+	for name, scope := range c.scopes {
+		child.scopes[name] = scope
+	}
+	return child
+}
+
+// attach adds the child, so it's linked to the registrations of the container again when they're changed
+func (c *Container) attach(child *Container) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, each := range c.children {
+		if each == child {
+			return
+		}
+	}
+	c.children = append(c.children, child)
+}
+
+// detach removes the child, e.g. it's closed or restored to another parent
+func (c *Container) detach(child *Container) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, each := range c.children {
+		if each == child {
+			c.children = append(c.children[:i:i], c.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// refreshChildren links the children to the current registrations of the container, the singletons of the
+// children relying on the replaced structs are built again
+func (c *Container) refreshChildren(replaced map[string]bool) {
+	c.lock.RLock()
+	children := append([]*Container{}, c.children...)
+	c.lock.RUnlock()
+	for _, child := range children {
+		if err := child.refreshParent(replaced); err != nil {
+			log.Printf("vial: the child container keeps the former beans of its parent: %v", err)
+		}
+	}
+}
+
+// refreshParent checks the registrations again with the current ones of the parent, the inherited structs are
+// the ones replaced in the parent
+func (c *Container) refreshParent(inherited map[string]bool) error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	old, err := c.current()
+	// the child links the parent in Done, and the closed one builds nothing
+	if err != nil || c.isClosed() {
+		return nil
+	}
+	r := newRegisterCloner().clone(old.register)
+	if err = c.linkParent(r); err != nil {
+		return err
+	}
+	if err = r.ScanAndCheck(c.scopes, c.properties); err != nil {
+		return err
+	}
+	l := newCollection(c, r)
+	replaced := r.dependents(nil, inherited)
+	l.keep(old, replaced)
+	c.publish(l)
+	destroyDropped(old, l)
+
+	// the grandchildren may rely on the structs replaced in any ancestor
+	for name := range inherited {
+		replaced[name] = true
+	}
+	c.refreshChildren(replaced)
+	return nil
+}
+
+// linkParent links the register to the parent one before the checks, the parent should be initialized first
+func (c *Container) linkParent(r *register) error {
+	if c.parent == nil {
+		return nil
+	}
+	parent, err := c.parent.current()
+	if err != nil {
+		return fmt.Errorf("the parent container hasn't been initialized")
+	}
+	r.parent = parent.register
+	return nil
+}
+
+// findStruct finds the struct in the register and then in its ancestors
+func (r *register) findStruct(name string) *structMetaInfo {
+	for each := r; each != nil; each = each.parent {
+		if meta, ok := each.sMap[name]; ok {
+			return meta
+		}
+	}
+	return nil
+}
+
+// lookupStruct finds the key of the struct with the bean name in the register and then in its ancestors
+func (r *register) lookupStruct(id string, name string) (key string, inherited bool, found bool) {
+	for each := r; each != nil; each = each.parent {
+		if key, found = each.structKey(id, name); found {
+			return key, each != r, true
+		}
+	}
+	key, _ = r.structKey(id, name)
+	return key, false, false
+}
+
+// findInterface finds the binding in the register and then in its ancestors
+func (r *register) findInterface(name string) *interfaceMetaInfo {
+	for each := r; each != nil; each = each.parent {
+		if bindInfo, ok := each.iMap[name]; ok {
+			return bindInfo
+		}
+	}
+	return nil
+}
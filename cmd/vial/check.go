@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GarrickZ2/vial"
+)
+
+// implementations returns all the bound structs, the primary one first and the others ordered by bean name
+func (b *binding) implementations() []string {
+	result := []string{b.primary}
+	for _, name := range sortedKeys(b.nameMapping) {
+		if b.nameMapping[name] != b.primary {
+			result = append(result, b.nameMapping[name])
+		}
+	}
+	return result
+}
+
+func (w *wiring) dependencyTargets(info *dependency) []string {
+	if info.absent {
+		return nil
+	}
+	switch info.kind {
+	case valueKind:
+		return nil
+	case interfaceSliceKind, interfaceMapKind:
+		return w.iMap[info.name].implementations()
+	default:
+		return []string{info.reference}
+	}
+}
+
+// scanAndCheck runs the same checks as vial.Done, and resolves the qualifiers
+func (w *wiring) scanAndCheck() []error {
+	var errs []error
+
+	// 1. Scan and Check interface
+	for _, interfaceID := range sortedKeys(w.iMap) {
+		eachInterface := w.iMap[interfaceID]
+		eachInterface.nameMapping = make(map[string]string)
+		for _, eachQualifier := range sortedKeys(eachInterface.others) {
+			if meta, ok := w.sMap[eachQualifier]; ok {
+				if name, exist := eachInterface.nameMapping[meta.name]; exist {
+					errs = append(errs, &vial.NameConflictError{Name: meta.name, Interface: interfaceID, Structs: []string{name, eachQualifier}})
+					continue
+				}
+				eachInterface.nameMapping[meta.name] = eachQualifier
+			} else {
+				errs = append(errs, &vial.MissingBindingError{Name: eachQualifier, Path: []string{interfaceID, eachQualifier}})
+			}
+		}
+	}
+
+	// 2. scan struct and find missing dependencies and all cycle injections
+	checker := &dependencyChecker{
+		wiring: w,
+		status: make(map[string]int),
+		cycles: make(map[string]bool),
+	}
+	for _, name := range sortedKeys(w.sMap) {
+		checker.check(name, name)
+	}
+	return append(errs, checker.errs...)
+}
+
+type dependencyChecker struct {
+	wiring *wiring
+	// 0 - not start, 1 - pending, 2 - done
+	status map[string]int
+	nodes  []string
+	path   []string
+	cycles map[string]bool
+	errs   []error
+}
+
+func (d *dependencyChecker) currentPath(last string) []string {
+	path := make([]string, 0, len(d.path)+1)
+	path = append(path, d.path...)
+	return append(path, last)
+}
+
+func (d *dependencyChecker) check(name string, checkName string) {
+	if d.status[name] != 0 {
+		return
+	}
+	d.status[name] = 1
+	d.nodes = append(d.nodes, name)
+	d.path = append(d.path, checkName)
+	defer func() {
+		d.nodes = d.nodes[:len(d.nodes)-1]
+		d.path = d.path[:len(d.path)-1]
+	}()
+
+	for _, info := range d.wiring.sMap[name].dependency {
+		if info.kind == valueKind {
+			continue
+		}
+		info.absent = false
+		if info.kind == structKind {
+			name := info.name
+			// the generator has one struct of each type, the qualifier should be its bean name
+			if meta, ok := d.wiring.sMap[name]; ok && info.qualifier != "" && meta.name != info.qualifier {
+				name = info.name + "#" + info.qualifier
+			}
+			if _, ok := d.wiring.sMap[name]; !ok && info.optional {
+				info.absent = true
+				continue
+			}
+			d.checkDependency(name, name)
+			continue
+		}
+		bindInfo, exist := d.wiring.iMap[info.name]
+		if !exist && info.optional {
+			info.absent = true
+			continue
+		}
+		if !exist {
+			d.errs = append(d.errs, &vial.MissingBindingError{Name: info.name, Interface: true, Path: d.currentPath(info.name)})
+			continue
+		}
+		if info.kind == interfaceSliceKind || info.kind == interfaceMapKind {
+			for _, each := range bindInfo.implementations() {
+				d.checkDependency(each, fmt.Sprintf("%v(%v)", info.name, each))
+			}
+			continue
+		}
+		if info.qualifier != "" {
+			if mapping, found := bindInfo.nameMapping[info.qualifier]; found {
+				info.reference = mapping
+			} else {
+				d.errs = append(d.errs, &vial.QualifierNotFoundError{Qualifier: info.qualifier, Interface: info.name, Path: d.currentPath(info.name)})
+				continue
+			}
+		} else {
+			info.reference = bindInfo.primary
+		}
+		d.checkDependency(info.reference, fmt.Sprintf("%v(%v)", info.name, info.reference))
+	}
+	d.status[name] = 2
+	d.wiring.order = append(d.wiring.order, name)
+}
+
+func (d *dependencyChecker) checkDependency(nextName string, checkName string) {
+	if _, ok := d.wiring.sMap[nextName]; !ok {
+		d.errs = append(d.errs, &vial.MissingBindingError{Name: nextName, Path: d.currentPath(checkName)})
+		return
+	}
+	if d.status[nextName] == 1 {
+		d.addCycle(nextName, checkName)
+		return
+	}
+	d.check(nextName, checkName)
+}
+
+func (d *dependencyChecker) addCycle(name string, checkName string) {
+	start := len(d.nodes) - 1
+	for d.nodes[start] != name {
+		start--
+	}
+	members := d.nodes[start:]
+	first := 0
+	for i, each := range members {
+		if each < members[first] {
+			first = i
+		}
+	}
+	key := strings.Join(append(append([]string{}, members[first:]...), members[:first]...), "|")
+	if d.cycles[key] {
+		return
+	}
+	d.cycles[key] = true
+	path := append([]string{name}, d.path[start+1:]...)
+	d.errs = append(d.errs, &vial.CycleError{Path: append(path, checkName)})
+}
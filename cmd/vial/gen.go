@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type generator struct {
+	w        *wiring
+	typeName string
+	imports  map[string]string
+	aliases  map[string]bool
+	methods  map[string]string
+	body     bytes.Buffer
+}
+
+func newGenerator(w *wiring, typeName string) *generator {
+	return &generator{
+		w:        w,
+		typeName: typeName,
+		imports:  make(map[string]string),
+		aliases:  make(map[string]bool),
+		methods:  make(map[string]string),
+	}
+}
+
+func (g *generator) use(path string, name string) string {
+	if alias, ok := g.imports[path]; ok {
+		return alias
+	}
+	alias := name
+	for i := 2; g.aliases[alias] || token.Lookup(alias).IsKeyword(); i++ {
+		alias = fmt.Sprintf("%v%d", name, i)
+	}
+	g.imports[path] = alias
+	g.aliases[alias] = true
+	return alias
+}
+
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg.Path() == g.w.pkg.PkgPath {
+		return ""
+	}
+	return g.use(pkg.Path(), pkg.Name())
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+// accessible checks the type can be referred in the generated code
+func (g *generator) accessible(t types.Type) error {
+	switch data := types.Unalias(t).(type) {
+	case *types.Pointer:
+		return g.accessible(data.Elem())
+	case *types.Slice:
+		return g.accessible(data.Elem())
+	case *types.Map:
+		if err := g.accessible(data.Key()); err != nil {
+			return err
+		}
+		return g.accessible(data.Elem())
+	case *types.Named:
+		obj := data.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() != g.w.pkg.PkgPath && !obj.Exported() {
+			return fmt.Errorf("unexported type %v cannot be used by the generated code", getQualifiedClassName(t))
+		}
+	}
+	return nil
+}
+
+func exportName(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return name
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func (g *generator) assignMethods() {
+	used := map[string]bool{"Close": true}
+	assign := func(id string, t types.Type) {
+		concrete, level := getConcreteType(t)
+		base := exportName(typeName(types.Unalias(concrete))) + strings.Repeat("Ptr", level)
+		name := base
+		if used[name] {
+			if named, ok := types.Unalias(concrete).(*types.Named); ok && named.Obj().Pkg() != nil {
+				name = exportName(named.Obj().Pkg().Name()) + base
+			}
+		}
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%v%d", base, i)
+		}
+		used[name] = true
+		g.methods[id] = name
+	}
+	for _, id := range sortedKeys(g.w.sMap) {
+		assign(id, g.w.sMap[id].typ)
+	}
+	for _, id := range sortedKeys(g.w.iMap) {
+		assign(id, g.w.iMap[id].typ)
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *generator) generate() ([]byte, error) {
+	for _, id := range sortedKeys(g.w.sMap) {
+		b := g.w.sMap[id]
+		if err := g.accessible(b.typ); err != nil {
+			return nil, fmt.Errorf("%v: %w", b.pos, err)
+		}
+		if b.constructor != nil && b.constructor.Pkg().Path() != g.w.pkg.PkgPath && !b.constructor.Exported() {
+			return nil, fmt.Errorf("%v: unexported constructor %v cannot be used by the generated code", b.pos, b.constructor.FullName())
+		}
+		for _, info := range b.dependency {
+			if err := g.accessible(info.typ); err != nil {
+				return nil, fmt.Errorf("%v: %w", b.pos, err)
+			}
+		}
+	}
+	g.assignMethods()
+
+	// 1. the injector holds the singletons
+	g.printf("// %v is the static wiring generated from the vial registrations.\n", g.typeName)
+	g.printf("// The singletons are created on the first Get, and destroyed by Close.\n")
+	g.printf("type %v struct {\n", g.typeName)
+	for _, id := range sortedKeys(g.w.sMap) {
+		if b := g.w.sMap[id]; !b.prototype {
+			method := g.methods[id]
+			g.printf("lock%v sync.Mutex\ndone%v bool\nvalue%v %v\n", method, method, method, g.typeString(b.typ))
+			g.use("sync", "sync")
+		}
+	}
+	g.printf("}\n\n")
+	g.printf("func New%v() *%v {\nreturn &%v{}\n}\n\n", exportName(g.typeName), g.typeName, g.typeName)
+
+	// 2. getters and builders
+	for _, id := range sortedKeys(g.w.sMap) {
+		g.generateBean(g.w.sMap[id])
+	}
+	for _, id := range sortedKeys(g.w.iMap) {
+		bindInfo := g.w.iMap[id]
+		g.printf("func (i *%v) Get%v() (%v, error) {\nreturn i.Get%v()\n}\n\n",
+			g.typeName, g.methods[id], g.typeString(bindInfo.typ), g.methods[bindInfo.primary])
+	}
+	g.generateClose()
+	g.generateHelpers()
+
+	var file bytes.Buffer
+	file.WriteString("// Code generated by vial gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&file, "package %v\n\n", g.w.pkg.Name)
+	file.WriteString("import (\n")
+	for _, path := range sortedKeys(g.imports) {
+		if alias := g.imports[path]; alias != lastSegment(path) {
+			fmt.Fprintf(&file, "%v %q\n", alias, path)
+		} else {
+			fmt.Fprintf(&file, "%q\n", path)
+		}
+	}
+	file.WriteString(")\n\n")
+	file.Write(g.body.Bytes())
+	return format.Source(file.Bytes())
+}
+
+func lastSegment(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func (g *generator) generateBean(b *bean) {
+	method := g.methods[b.id]
+	typ := g.typeString(b.typ)
+	if b.prototype {
+		g.printf("func (i *%v) Get%v() (%v, error) {\nreturn i.build%v()\n}\n\n", g.typeName, method, typ, method)
+	} else {
+		g.printf("func (i *%v) Get%v() (%v, error) {\n", g.typeName, method, typ)
+		g.printf("i.lock%v.Lock()\ndefer i.lock%v.Unlock()\n", method, method)
+		g.printf("if i.done%v {\nreturn i.value%v, nil\n}\n", method, method)
+		g.printf("value, err := i.build%v()\nif err != nil {\nreturn value, err\n}\n", method)
+		g.printf("i.value%v, i.done%v = value, true\nreturn value, nil\n}\n\n", method, method)
+	}
+
+	g.printf("func (i *%v) build%v() (result %v, err error) {\n", g.typeName, method, typ)
+	args := make([]string, 0, len(b.dependency))
+	for index, info := range b.dependency {
+		args = append(args, g.generateDependency(fmt.Sprintf("d%d", index), info))
+	}
+	if b.constructor != nil {
+		call := b.constructor.Name()
+		if b.constructor.Pkg().Path() != g.w.pkg.PkgPath {
+			call = g.use(b.constructor.Pkg().Path(), b.constructor.Pkg().Name()) + "." + call
+		}
+		if b.constructErr {
+			g.printf("result, err = %v(%v)\nif err != nil {\nreturn\n}\n", call, strings.Join(args, ", "))
+		} else {
+			g.printf("result = %v(%v)\n", call, strings.Join(args, ", "))
+		}
+	} else {
+		concrete, level := getConcreteType(b.typ)
+		g.printf("value := %v{", g.typeString(concrete))
+		for index, info := range b.dependency {
+			g.printf("\n%v: %v,", info.field, args[index])
+		}
+		g.printf("\n}\n")
+		value := "value"
+		for j := 0; j < level; j++ {
+			g.printf("value%d := &%v\n", j+1, value)
+			value = fmt.Sprintf("value%d", j+1)
+		}
+		g.printf("result = %v\n", value)
+	}
+	g.generateHook(b, b.initMethod, "vialInit", fmt.Sprintf("init %v failed: %%w", b.id))
+	g.printf("return\n}\n\n")
+}
+
+// generateDependency writes the statements to build the dependency, and returns the expression of it
+func (g *generator) generateDependency(name string, info *dependency) string {
+	switch {
+	case info.kind == valueKind:
+		return info.literal
+	case info.absent:
+		g.printf("var %v %v\n", name, g.typeString(info.typ))
+		return name
+	case info.kind == interfaceSliceKind || info.kind == interfaceMapKind:
+		bindInfo := g.w.iMap[info.name]
+		if info.kind == interfaceSliceKind {
+			g.printf("%v := make(%v, 0, %d)\n", name, g.typeString(info.typ), len(bindInfo.others))
+		} else {
+			g.printf("%v := make(%v, %d)\n", name, g.typeString(info.typ), len(bindInfo.nameMapping))
+		}
+		for index, each := range bindInfo.implementations() {
+			g.printf("%v_%d, err := i.Get%v()\nif err != nil {\nreturn result, err\n}\n", name, index, g.methods[each])
+			if info.kind == interfaceSliceKind {
+				g.printf("%v = append(%v, %v_%d)\n", name, name, name, index)
+			} else {
+				g.printf("%v[%v] = %v_%d\n", name, strconv.Quote(g.w.sMap[each].name), name, index)
+			}
+		}
+		return name
+	default:
+		g.printf("%v, err := i.Get%v()\nif err != nil {\nreturn result, err\n}\n", name, g.methods[info.reference])
+		return name
+	}
+}
+
+// generateHook calls the lifecycle hook of the result like vial does
+func (g *generator) generateHook(b *bean, method string, helper string, message string) {
+	_, level := getConcreteType(b.typ)
+	if method == "" && level > 1 {
+		return
+	}
+	receiver := "result"
+	if method == "" && level == 0 && !types.IsInterface(b.typ) {
+		receiver = "&result"
+	}
+	if level == 1 {
+		g.printf("if result != nil {\n")
+	}
+	if method == "" {
+		g.printf("if err = %v(%v); err != nil {\n", helper, receiver)
+	} else if hookReturnsError(b.typ, method) {
+		g.printf("if err = %v.%v(); err != nil {\n", receiver, method)
+	} else {
+		g.printf("%v.%v()\n", receiver, method)
+	}
+	if method == "" || hookReturnsError(b.typ, method) {
+		g.printf("return result, fmt.Errorf(%q, err)\n}\n", message)
+		g.use("fmt", "fmt")
+	}
+	if level == 1 {
+		g.printf("}\n")
+	}
+}
+
+func hookReturnsError(dataType types.Type, method string) bool {
+	receiverType := dataType
+	if _, ok := dataType.(*types.Pointer); !ok {
+		receiverType = types.NewPointer(dataType)
+	}
+	selection := types.NewMethodSet(receiverType).Lookup(nil, method)
+	return selection.Type().(*types.Signature).Results().Len() == 1
+}
+
+func (g *generator) generateClose() {
+	g.printf("// Close destroys the created singletons in the reverse order of their dependencies\n")
+	g.printf("func (i *%v) Close() error {\nerrs := &%v.MultiError{}\n", g.typeName, g.use(vialPath, "vial"))
+	for index := len(g.w.order) - 1; index >= 0; index-- {
+		b := g.w.sMap[g.w.order[index]]
+		if b.prototype {
+			continue
+		}
+		method := g.methods[b.id]
+		g.printf("i.lock%v.Lock()\nif i.done%v {\n", method, method)
+		_, level := getConcreteType(b.typ)
+		receiver := fmt.Sprintf("i.value%v", method)
+		if level == 0 && !types.IsInterface(b.typ) && b.destroyMethod == "" {
+			receiver = "&" + receiver
+		}
+		if level <= 1 || b.destroyMethod != "" {
+			if level == 1 {
+				g.printf("if %v != nil {\n", receiver)
+			}
+			switch {
+			case b.destroyMethod == "":
+				g.printf("if err := vialDestroy(%v); err != nil {\n", receiver)
+			case hookReturnsError(b.typ, b.destroyMethod):
+				g.printf("if err := %v.%v(); err != nil {\n", receiver, b.destroyMethod)
+			default:
+				g.printf("%v.%v()\n", receiver, b.destroyMethod)
+			}
+			if b.destroyMethod == "" || hookReturnsError(b.typ, b.destroyMethod) {
+				g.printf("errs.Errors = append(errs.Errors, fmt.Errorf(%q, err))\n}\n", fmt.Sprintf("destroy %v failed: %%w", b.id))
+				g.use("fmt", "fmt")
+			}
+			if level == 1 {
+				g.printf("}\n")
+			}
+		}
+		g.printf("var zero %v\ni.value%v, i.done%v = zero, false\n}\ni.lock%v.Unlock()\n", g.typeString(b.typ), method, method, method)
+	}
+	g.printf("if len(errs.Errors) == 0 {\nreturn nil\n}\nreturn errs\n}\n\n")
+}
+
+func (g *generator) generateHelpers() {
+	vialAlias := g.use(vialPath, "vial")
+	g.printf("func vialInit(bean interface{}) error {\n")
+	g.printf("if initializer, ok := bean.(%v.Initializer); ok {\nreturn initializer.Init()\n}\nreturn nil\n}\n\n", vialAlias)
+	g.printf("func vialDestroy(bean interface{}) error {\n")
+	g.printf("if destroyer, ok := bean.(%v.Destroyer); ok {\nreturn destroyer.Destroy()\n}\n", vialAlias)
+	g.printf("if closer, ok := bean.(%v.Closer); ok {\nreturn closer.Close()\n}\nreturn nil\n}\n", g.use("io", "io"))
+}
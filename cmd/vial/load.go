@@ -0,0 +1,496 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/GarrickZ2/vial"
+	"golang.org/x/tools/go/packages"
+)
+
+const vialPath = "github.com/GarrickZ2/vial"
+
+type kindType int
+
+const (
+	valueKind kindType = iota
+	structKind
+	interfaceKind
+	interfaceSliceKind
+	interfaceMapKind
+)
+
+type bean struct {
+	id            string
+	typ           types.Type
+	name          string
+	prototype     bool
+	constructor   *types.Func
+	constructErr  bool
+	initMethod    string
+	destroyMethod string
+	dependency    []*dependency
+	pos           token.Position
+}
+
+type dependency struct {
+	field     string
+	typ       types.Type
+	kind      kindType
+	name      string
+	qualifier string
+	reference string
+	optional  bool
+	absent    bool
+	literal   string
+}
+
+type binding struct {
+	id          string
+	typ         types.Type
+	primary     string
+	others      map[string]bool
+	nameMapping map[string]string
+}
+
+// wiring collects all the registrations in the package, like the register in vial
+type wiring struct {
+	pkg      *packages.Package
+	sMap     map[string]*bean
+	iMap     map[string]*binding
+	order    []string
+	errs     []error
+	problems []string
+}
+
+func (w *wiring) fail(pos token.Pos, err error) {
+	w.errs = append(w.errs, err)
+	w.problems = append(w.problems, fmt.Sprintf("%v: %v", w.pkg.Fset.Position(pos), err))
+}
+
+func loadPackage(dir string) (*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes |
+			packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Dir: dir,
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expect one package in %v, found %d", dir, len(pkgs))
+	}
+	if len(pkgs[0].Errors) > 0 {
+		return nil, pkgs[0].Errors[0]
+	}
+	return pkgs[0], nil
+}
+
+// collect finds the registrations on the default container. If funcs is not empty,
+// only the calls inside these functions are collected.
+func collect(pkg *packages.Package, funcs map[string]bool, output string) *wiring {
+	w := &wiring{
+		pkg:  pkg,
+		sMap: make(map[string]*bean),
+		iMap: make(map[string]*binding),
+	}
+	for _, file := range pkg.Syntax {
+		if strings.HasSuffix(pkg.Fset.Position(file.Pos()).Filename, output) {
+			continue
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || (len(funcs) > 0 && !funcs[fn.Name.Name]) {
+				continue
+			}
+			ast.Inspect(fn.Body, func(node ast.Node) bool {
+				if call, isCall := node.(*ast.CallExpr); isCall {
+					w.collectCall(call)
+				}
+				return true
+			})
+		}
+	}
+	return w
+}
+
+// vialFunc returns the vial function or method called by the expression and its type arguments
+func (w *wiring) vialFunc(expr ast.Expr) (string, []types.Type, bool) {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(expr).(type) {
+	case *ast.IndexExpr:
+		return w.vialFunc(fun.X)
+	case *ast.IndexListExpr:
+		return w.vialFunc(fun.X)
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return "", nil, false
+	}
+	fn, ok := w.pkg.TypesInfo.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != vialPath {
+		return "", nil, false
+	}
+	var typeArgs []types.Type
+	if instance, found := w.pkg.TypesInfo.Instances[ident]; found {
+		for i := 0; i < instance.TypeArgs.Len(); i++ {
+			typeArgs = append(typeArgs, instance.TypeArgs.At(i))
+		}
+	}
+	method := fn.Type().(*types.Signature).Recv() != nil
+	return fn.Name(), typeArgs, method
+}
+
+func (w *wiring) collectCall(call *ast.CallExpr) {
+	name, typeArgs, method := w.vialFunc(call.Fun)
+	if method {
+		w.collectMethodCall(call, name)
+		return
+	}
+	switch name {
+	case "RegisterStruct":
+		if w.checkCall(call, name, typeArgs, 1, 0) {
+			w.registerStruct(call, typeArgs[0], call.Args)
+		}
+	case "RegisterStructByInstance":
+		if w.checkCall(call, name, typeArgs, 0, 1) {
+			w.registerStruct(call, w.pkg.TypesInfo.TypeOf(call.Args[0]), call.Args[1:])
+		}
+	case "RegisterConstructor":
+		if w.checkCall(call, name, typeArgs, 0, 1) {
+			w.registerConstructor(call)
+		}
+	case "Bind":
+		if w.checkCall(call, name, typeArgs, 2, 0) {
+			w.bind(call, typeArgs[0], typeArgs[1], call.Args)
+		}
+	case "BindByInstance":
+		if !w.checkCall(call, name, typeArgs, 0, 2) {
+			return
+		}
+		interfaceType := w.pkg.TypesInfo.TypeOf(call.Args[0])
+		if pointer, ok := interfaceType.(*types.Pointer); ok {
+			interfaceType = pointer.Elem()
+		}
+		w.bind(call, interfaceType, w.pkg.TypesInfo.TypeOf(call.Args[1]), call.Args[2:])
+	case "RegisterInstance", "TryRegisterInstance", "RegisterScope", "TryRegisterScope", "RegisterConfig", "TryRegisterConfig",
+		"Override", "TryOverride", "OverrideByInstance", "TryOverrideByInstance", "RegisterConverter", "NamedStruct":
+		w.fail(call.Pos(), fmt.Errorf("%v cannot be compiled into static wiring", name))
+	case "TryRegisterStruct", "TryRegisterStructByInstance", "TryRegisterConstructor", "TryBind", "TryBindByInstance":
+		w.fail(call.Pos(), fmt.Errorf("%v is not supported by the generator, use %v instead", name, strings.TrimPrefix(name, "Try")))
+	case "RegisterStructToContainer", "TryRegisterStructToContainer", "BindToContainer", "TryBindToContainer",
+		"RegisterConfigToContainer", "TryRegisterConfigToContainer", "OverrideInContainer", "TryOverrideInContainer":
+		w.fail(call.Pos(), fmt.Errorf("%v cannot be compiled into static wiring, only the default container is supported", name))
+	}
+}
+
+// collectMethodCall reports the registrations on a Container, the generator only wires the default container
+func (w *wiring) collectMethodCall(call *ast.CallExpr, name string) {
+	switch name {
+	case "RegisterStructByInstance", "TryRegisterStructByInstance", "RegisterConstructor", "TryRegisterConstructor",
+		"RegisterValue", "TryRegisterValue", "Bind", "TryBind", "RegisterScope", "TryRegisterScope",
+		"RegisterConfig", "TryRegisterConfig", "Override", "TryOverride":
+		w.fail(call.Pos(), fmt.Errorf("container method %v cannot be compiled into static wiring, only the default container is supported", name))
+	}
+}
+
+// checkCall checks the call has the type arguments and the leading arguments the registration needs
+func (w *wiring) checkCall(call *ast.CallExpr, name string, typeArgs []types.Type, typeCount int, argCount int) bool {
+	if len(typeArgs) != typeCount || len(call.Args) < argCount || call.Ellipsis.IsValid() {
+		w.fail(call.Pos(), fmt.Errorf("this call of %v cannot be compiled into static wiring", name))
+		return false
+	}
+	return true
+}
+
+func (w *wiring) applyOptions(b *bean, args []ast.Expr) {
+	for _, arg := range args {
+		call, ok := ast.Unparen(arg).(*ast.CallExpr)
+		if !ok {
+			w.fail(arg.Pos(), fmt.Errorf("option of %v should be a direct call of vial options", b.id))
+			continue
+		}
+		name, _, _ := w.vialFunc(call.Fun)
+		var literal string
+		if len(call.Args) == 1 {
+			tv := w.pkg.TypesInfo.Types[call.Args[0]]
+			if tv.Value == nil || tv.Value.Kind() != constant.String {
+				w.fail(call.Args[0].Pos(), fmt.Errorf("param of %v should be a constant string", name))
+				continue
+			}
+			literal = constant.StringVal(tv.Value)
+		}
+		switch name {
+		case "WithSingleton":
+			b.prototype = false
+		case "WithProtoType":
+			b.prototype = true
+		case "WithName":
+			b.name = literal
+		case "WithPrimary":
+			// every type has one bean in the static wiring, so it's always the primary one
+		case "WithInitMethod":
+			b.initMethod = literal
+		case "WithDestroyMethod":
+			b.destroyMethod = literal
+		case "WithScope":
+			switch literal {
+			case "singleton":
+				b.prototype = false
+			case "prototype":
+				b.prototype = true
+			default:
+				w.fail(call.Pos(), fmt.Errorf("scope %v of %v cannot be compiled into static wiring", literal, b.id))
+			}
+		default:
+			w.fail(call.Pos(), fmt.Errorf("option %v of %v cannot be compiled into static wiring", name, b.id))
+		}
+	}
+}
+
+func (w *wiring) registerStruct(call *ast.CallExpr, inputType types.Type, options []ast.Expr) {
+	// 1. Check the first input is valid
+	structureType, _ := getConcreteType(inputType)
+	id := getQualifiedClassName(inputType)
+	structure, ok := structureType.Underlying().(*types.Struct)
+	if !ok {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: fmt.Errorf("Input elem %v is not a struct related type", id)})
+		return
+	}
+
+	// 2. Check the elem existence, the named beans of the same type are resolved by the runtime container only
+	if _, exist := w.sMap[id]; exist {
+		w.fail(call.Pos(), fmt.Errorf("%v is registered again, the beans of the same type cannot be compiled into static wiring", id))
+		return
+	}
+
+	// 3. Check and register the dependency
+	result := &bean{id: id, typ: inputType, name: typeName(structureType), pos: w.pkg.Fset.Position(call.Pos())}
+	for i := 0; i < structure.NumFields(); i++ {
+		field := structure.Field(i)
+		tag := reflect.StructTag(structure.Tag(i))
+		if val, found := tag.Lookup("value"); found {
+			if !field.Exported() {
+				w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: fmt.Errorf("Input type %v contains field %v is unexported, cannot set as auto-wired", id, field.Name())})
+				return
+			}
+			if strings.Contains(val, "${") {
+				w.fail(call.Pos(), fmt.Errorf("value placeholder %v of %v cannot be compiled into static wiring", val, id))
+				return
+			}
+			literal, err := valueLiteral(field.Type(), val)
+			if err != nil {
+				w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: fmt.Errorf("Parsing Value Tag Error: %w", err)})
+				return
+			}
+			result.dependency = append(result.dependency, &dependency{field: field.Name(), typ: field.Type(), kind: valueKind, literal: literal})
+		} else if isInjectedField(tag) {
+			if !field.Exported() {
+				w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: fmt.Errorf("Input type %v contains field %v is unexported, cannot set as auto-wired", id, field.Name())})
+				return
+			}
+			info, err := newDependency(field.Type())
+			if err != nil {
+				w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: err})
+				return
+			}
+			info.field = field.Name()
+			info.qualifier = tag.Get("qualifier")
+			_, info.optional = tag.Lookup("optional")
+			result.dependency = append(result.dependency, info)
+		}
+	}
+
+	// 4. set the options
+	w.applyOptions(result, options)
+	if err := validateHookOption(inputType, result); err != nil {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: err})
+		return
+	}
+
+	// 5. register in the map
+	w.sMap[id] = result
+}
+
+func (w *wiring) registerConstructor(call *ast.CallExpr) {
+	// 1. check input type
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Args[0]).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	}
+	constructor, _ := w.pkg.TypesInfo.Uses[ident].(*types.Func)
+	if ident == nil || constructor == nil {
+		w.fail(call.Pos(), fmt.Errorf("constructor should be a package level function to generate static wiring"))
+		return
+	}
+	signature := constructor.Type().(*types.Signature)
+	if signature.Results().Len() == 0 || signature.Results().Len() > 2 {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: constructor.FullName(), Err: fmt.Errorf("constructor can only return 1 or 2 data")})
+		return
+	}
+	if signature.Variadic() || signature.TypeParams().Len() > 0 {
+		w.fail(call.Pos(), fmt.Errorf("constructor %v cannot be variadic or generic", constructor.FullName()))
+		return
+	}
+
+	// 2.1 check return type 1
+	inputType := signature.Results().At(0).Type()
+	concreteType, _ := getConcreteType(inputType)
+	id := getQualifiedClassName(inputType)
+	if _, ok := w.sMap[id]; ok {
+		w.fail(call.Pos(), fmt.Errorf("%v is registered again, the beans of the same type cannot be compiled into static wiring", id))
+		return
+	}
+
+	// 2.2 check return type 2
+	result := &bean{id: id, typ: inputType, name: typeName(concreteType), constructor: constructor, pos: w.pkg.Fset.Position(call.Pos())}
+	if signature.Results().Len() == 2 {
+		if !types.Implements(signature.Results().At(1).Type(), errorInterface) {
+			w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: fmt.Errorf("The second out type of the constructor should be error or implement error interface")})
+			return
+		}
+		result.constructErr = true
+	}
+
+	// 3. Check dependency (input data)
+	for i := 0; i < signature.Params().Len(); i++ {
+		info, err := newDependency(signature.Params().At(i).Type())
+		if err != nil {
+			w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: err})
+			return
+		}
+		result.dependency = append(result.dependency, info)
+	}
+
+	// 4. apply the option
+	w.applyOptions(result, call.Args[1:])
+	if err := validateHookOption(inputType, result); err != nil {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: id, Err: err})
+		return
+	}
+
+	// 5. add to the map
+	w.sMap[id] = result
+}
+
+func (w *wiring) bind(call *ast.CallExpr, interfaceType types.Type, primaryType types.Type, others []ast.Expr) {
+	interfaceID := getQualifiedClassName(interfaceType)
+	iface, ok := interfaceType.Underlying().(*types.Interface)
+	if !ok {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: interfaceID, Err: fmt.Errorf("Input type %v is not an interface", interfaceID)})
+		return
+	}
+	if _, exist := w.iMap[interfaceID]; exist {
+		w.fail(call.Pos(), &vial.DuplicateRegistrationError{ID: interfaceID, Interface: true})
+		return
+	}
+	result := &binding{id: interfaceID, typ: interfaceType, others: make(map[string]bool)}
+
+	if !types.Implements(primaryType, iface) {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: interfaceID, Err: fmt.Errorf("The primary struct type %v not implement the interface %v", getQualifiedClassName(primaryType), interfaceID)})
+		return
+	}
+	result.primary = getQualifiedClassName(primaryType)
+
+	for _, each := range others {
+		otherType := w.pkg.TypesInfo.TypeOf(each)
+		if isOption(otherType) {
+			w.fail(each.Pos(), fmt.Errorf("options in the binding of %v cannot be compiled into static wiring", interfaceID))
+			return
+		}
+		otherID := getQualifiedClassName(otherType)
+		if !types.Implements(otherType, iface) {
+			w.fail(each.Pos(), &vial.InvalidDefinitionError{ID: interfaceID, Err: fmt.Errorf("The struct type %v not implement the interface %v", otherID, interfaceID)})
+			return
+		}
+		if result.others[otherID] {
+			w.fail(each.Pos(), &vial.InvalidDefinitionError{ID: interfaceID, Err: fmt.Errorf("Can not bind %v twice on the same interface", otherID)})
+			return
+		}
+		result.others[otherID] = true
+	}
+	if result.others[result.primary] {
+		w.fail(call.Pos(), &vial.InvalidDefinitionError{ID: interfaceID, Err: fmt.Errorf("Can not bind %v twice on the same interface", result.primary)})
+		return
+	}
+	result.others[result.primary] = true
+	w.iMap[interfaceID] = result
+}
+
+func isInjectedField(tag reflect.StructTag) bool {
+	for _, each := range []string{"auto_wire", "value", "optional"} {
+		if _, exist := tag.Lookup(each); exist {
+			return true
+		}
+	}
+	return false
+}
+
+// isOption checks the type is the options of vial, which can be passed to Bind among the structs
+func isOption(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == vialPath && named.Obj().Name() == "applyOption"
+}
+
+func newDependency(dataType types.Type) (*dependency, error) {
+	if named, ok := dataType.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == vialPath {
+		return nil, fmt.Errorf("%v cannot be compiled into static wiring", named.Obj().Name())
+	}
+	kind := getKindType(dataType)
+	name := getQualifiedClassName(dataType)
+	switch kind {
+	case interfaceSliceKind:
+		name = getQualifiedClassName(dataType.Underlying().(*types.Slice).Elem())
+	case interfaceMapKind:
+		name = getQualifiedClassName(dataType.Underlying().(*types.Map).Elem())
+	}
+	return &dependency{typ: dataType, kind: kind, name: name, reference: name}, nil
+}
+
+var errorInterface = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func validateHookOption(dataType types.Type, b *bean) error {
+	for _, method := range []string{b.initMethod, b.destroyMethod} {
+		if method == "" {
+			continue
+		}
+		if err := validateHookMethod(dataType, method); err != nil {
+			return fmt.Errorf("Lifecycle Hook Error: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateHookMethod(dataType types.Type, method string) error {
+	if types.IsInterface(dataType) {
+		return fmt.Errorf("cannot find method %v on interface type %v", method, getQualifiedClassName(dataType))
+	}
+	receiverType := dataType
+	if _, ok := dataType.(*types.Pointer); !ok {
+		receiverType = types.NewPointer(dataType)
+	}
+	selection := types.NewMethodSet(receiverType).Lookup(nil, method)
+	if selection == nil || !token.IsExported(method) {
+		return fmt.Errorf("type %v has no exported method %v", getQualifiedClassName(dataType), method)
+	}
+	signature := selection.Type().(*types.Signature)
+	if signature.Params().Len() != 0 {
+		return fmt.Errorf("method %v of %v should not accept any params", method, getQualifiedClassName(dataType))
+	}
+	results := signature.Results()
+	if results.Len() > 1 || (results.Len() == 1 && !types.Identical(results.At(0).Type(), types.Universe.Lookup("error").Type())) {
+		return fmt.Errorf("method %v of %v can only return nothing or an error", method, getQualifiedClassName(dataType))
+	}
+	return nil
+}
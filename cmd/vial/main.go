@@ -0,0 +1,66 @@
+// Command vial compiles the vial registrations of a package into static wiring code.
+//
+// Usage:
+//
+//	vial [-func init,Provider] [-type VialInjector] [-output vial_gen.go] [dir]
+//
+// It finds the RegisterStruct, RegisterConstructor and Bind calls on the default container,
+// runs the same checks as vial.Done, and writes plain Go constructors with the singleton caching
+// and the qualifier resolution baked in.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	funcs := flag.String("func", "", "comma separated functions to find the registrations, all functions by default")
+	typeName := flag.String("type", "VialInjector", "name of the generated injector type")
+	output := flag.String("output", "vial_gen.go", "name of the generated file")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir, *funcs, *typeName, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, funcs string, typeName string, output string) error {
+	source, err := generate(dir, funcs, typeName, output)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, output), source, 0o644)
+}
+
+func generate(dir string, funcs string, typeName string, output string) ([]byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	funcSet := make(map[string]bool)
+	for _, each := range strings.Split(funcs, ",") {
+		if each = strings.TrimSpace(each); each != "" {
+			funcSet[each] = true
+		}
+	}
+	w := collect(pkg, funcSet, output)
+	if len(w.errs) == 0 {
+		for _, err = range w.scanAndCheck() {
+			w.errs = append(w.errs, err)
+			w.problems = append(w.problems, err.Error())
+		}
+	}
+	if len(w.problems) > 0 {
+		return nil, fmt.Errorf("vial: %d wiring problems found:\n%v", len(w.problems), strings.Join(w.problems, "\n"))
+	}
+	return newGenerator(w, typeName).generate()
+}
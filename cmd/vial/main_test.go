@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	// the golden file is part of the package, so the load also checks it compiles
+	source, err := generate("testdata/app", "", "Injector", "vial_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile("testdata/app/vial_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(source, golden) {
+		t.Errorf("generated code differs from testdata/app/vial_gen.go:\n%s", source)
+	}
+}
+
+func TestGenerateProblems(t *testing.T) {
+	_, err := generate("testdata/broken", "Cycle", "Injector", "vial_gen.go")
+	if err == nil || !strings.Contains(err.Error(), "Cycle Injection") {
+		t.Errorf("expect cycle injection error, got %v", err)
+	}
+
+	_, err = generate("testdata/broken", "Scoped", "Injector", "vial_gen.go")
+	if err == nil {
+		t.Fatal("expect wiring problems")
+	}
+	for _, expect := range []string{"broken.go:23", "WithRequestScope", "broken.go:24", "beans of the same type cannot be compiled"} {
+		if !strings.Contains(err.Error(), expect) {
+			t.Errorf("expect %q in error: %v", expect, err)
+		}
+	}
+
+	_, err = generate("testdata/broken", "Qualified", "Injector", "vial_gen.go")
+	if err == nil || !strings.Contains(err.Error(), "broken.C#replica") {
+		t.Errorf("expect the qualified struct not found, got %v", err)
+	}
+
+	_, err = generate("testdata/broken", "Containers", "Injector", "vial_gen.go")
+	if err == nil {
+		t.Fatal("expect the other containers to be reported")
+	}
+	for _, expect := range []string{"broken.go:40", "RegisterStructByInstance", "broken.go:41", "container method Bind", "broken.go:42", "RegisterStructToContainer"} {
+		if !strings.Contains(err.Error(), expect) {
+			t.Errorf("expect %q in error: %v", expect, err)
+		}
+	}
+}
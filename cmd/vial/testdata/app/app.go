@@ -0,0 +1,57 @@
+package app
+
+import (
+	"github.com/GarrickZ2/vial"
+)
+
+type Store interface {
+	Load(key string) string
+}
+
+type MemoryStore struct {
+	Prefix string `value:"memory"`
+}
+
+func (m *MemoryStore) Load(key string) string {
+	return m.Prefix + ":" + key
+}
+
+type FileStore struct {
+	Path *string `value:"/tmp/vial"`
+}
+
+func (f *FileStore) Load(key string) string {
+	return *f.Path + "/" + key
+}
+
+type Config struct {
+	Retry int
+}
+
+func NewConfig() (*Config, error) {
+	return &Config{Retry: 3}, nil
+}
+
+type Service struct {
+	Config  *Config          `auto_wire:""`
+	Store   Store            `auto_wire:""`
+	Backup  Store            `auto_wire:"" qualifier:"file"`
+	Stores  map[string]Store `auto_wire:""`
+	Missing *Cache           `optional:""`
+	Ready   bool
+}
+
+func (s *Service) Init() error {
+	s.Ready = true
+	return nil
+}
+
+type Cache struct{}
+
+func init() {
+	vial.RegisterStruct[*MemoryStore]()
+	vial.RegisterStruct[*FileStore](vial.WithName("file"))
+	vial.RegisterConstructor(NewConfig)
+	vial.RegisterStruct[*Service](vial.WithProtoType())
+	vial.Bind[Store, *MemoryStore](&FileStore{})
+}
@@ -0,0 +1,220 @@
+// Code generated by vial gen. DO NOT EDIT.
+
+package app
+
+import (
+	"fmt"
+	"github.com/GarrickZ2/vial"
+	"io"
+	"sync"
+)
+
+// Injector is the static wiring generated from the vial registrations.
+// The singletons are created on the first Get, and destroyed by Close.
+type Injector struct {
+	lockConfigPtr       sync.Mutex
+	doneConfigPtr       bool
+	valueConfigPtr      *Config
+	lockFileStorePtr    sync.Mutex
+	doneFileStorePtr    bool
+	valueFileStorePtr   *FileStore
+	lockMemoryStorePtr  sync.Mutex
+	doneMemoryStorePtr  bool
+	valueMemoryStorePtr *MemoryStore
+}
+
+func NewInjector() *Injector {
+	return &Injector{}
+}
+
+func (i *Injector) GetConfigPtr() (*Config, error) {
+	i.lockConfigPtr.Lock()
+	defer i.lockConfigPtr.Unlock()
+	if i.doneConfigPtr {
+		return i.valueConfigPtr, nil
+	}
+	value, err := i.buildConfigPtr()
+	if err != nil {
+		return value, err
+	}
+	i.valueConfigPtr, i.doneConfigPtr = value, true
+	return value, nil
+}
+
+func (i *Injector) buildConfigPtr() (result *Config, err error) {
+	result, err = NewConfig()
+	if err != nil {
+		return
+	}
+	if result != nil {
+		if err = vialInit(result); err != nil {
+			return result, fmt.Errorf("init *github.com/GarrickZ2/vial/cmd/vial/testdata/app.Config failed: %w", err)
+		}
+	}
+	return
+}
+
+func (i *Injector) GetFileStorePtr() (*FileStore, error) {
+	i.lockFileStorePtr.Lock()
+	defer i.lockFileStorePtr.Unlock()
+	if i.doneFileStorePtr {
+		return i.valueFileStorePtr, nil
+	}
+	value, err := i.buildFileStorePtr()
+	if err != nil {
+		return value, err
+	}
+	i.valueFileStorePtr, i.doneFileStorePtr = value, true
+	return value, nil
+}
+
+func (i *Injector) buildFileStorePtr() (result *FileStore, err error) {
+	value := FileStore{
+		Path: func() *string { v := "/tmp/vial"; return &v }(),
+	}
+	value1 := &value
+	result = value1
+	if result != nil {
+		if err = vialInit(result); err != nil {
+			return result, fmt.Errorf("init *github.com/GarrickZ2/vial/cmd/vial/testdata/app.FileStore failed: %w", err)
+		}
+	}
+	return
+}
+
+func (i *Injector) GetMemoryStorePtr() (*MemoryStore, error) {
+	i.lockMemoryStorePtr.Lock()
+	defer i.lockMemoryStorePtr.Unlock()
+	if i.doneMemoryStorePtr {
+		return i.valueMemoryStorePtr, nil
+	}
+	value, err := i.buildMemoryStorePtr()
+	if err != nil {
+		return value, err
+	}
+	i.valueMemoryStorePtr, i.doneMemoryStorePtr = value, true
+	return value, nil
+}
+
+func (i *Injector) buildMemoryStorePtr() (result *MemoryStore, err error) {
+	value := MemoryStore{
+		Prefix: "memory",
+	}
+	value1 := &value
+	result = value1
+	if result != nil {
+		if err = vialInit(result); err != nil {
+			return result, fmt.Errorf("init *github.com/GarrickZ2/vial/cmd/vial/testdata/app.MemoryStore failed: %w", err)
+		}
+	}
+	return
+}
+
+func (i *Injector) GetServicePtr() (*Service, error) {
+	return i.buildServicePtr()
+}
+
+func (i *Injector) buildServicePtr() (result *Service, err error) {
+	d0, err := i.GetConfigPtr()
+	if err != nil {
+		return result, err
+	}
+	d1, err := i.GetMemoryStorePtr()
+	if err != nil {
+		return result, err
+	}
+	d2, err := i.GetFileStorePtr()
+	if err != nil {
+		return result, err
+	}
+	d3 := make(map[string]Store, 2)
+	d3_0, err := i.GetMemoryStorePtr()
+	if err != nil {
+		return result, err
+	}
+	d3["MemoryStore"] = d3_0
+	d3_1, err := i.GetFileStorePtr()
+	if err != nil {
+		return result, err
+	}
+	d3["file"] = d3_1
+	var d4 *Cache
+	value := Service{
+		Config:  d0,
+		Store:   d1,
+		Backup:  d2,
+		Stores:  d3,
+		Missing: d4,
+	}
+	value1 := &value
+	result = value1
+	if result != nil {
+		if err = vialInit(result); err != nil {
+			return result, fmt.Errorf("init *github.com/GarrickZ2/vial/cmd/vial/testdata/app.Service failed: %w", err)
+		}
+	}
+	return
+}
+
+func (i *Injector) GetStore() (Store, error) {
+	return i.GetMemoryStorePtr()
+}
+
+// Close destroys the created singletons in the reverse order of their dependencies
+func (i *Injector) Close() error {
+	errs := &vial.MultiError{}
+	i.lockMemoryStorePtr.Lock()
+	if i.doneMemoryStorePtr {
+		if i.valueMemoryStorePtr != nil {
+			if err := vialDestroy(i.valueMemoryStorePtr); err != nil {
+				errs.Errors = append(errs.Errors, fmt.Errorf("destroy *github.com/GarrickZ2/vial/cmd/vial/testdata/app.MemoryStore failed: %w", err))
+			}
+		}
+		var zero *MemoryStore
+		i.valueMemoryStorePtr, i.doneMemoryStorePtr = zero, false
+	}
+	i.lockMemoryStorePtr.Unlock()
+	i.lockFileStorePtr.Lock()
+	if i.doneFileStorePtr {
+		if i.valueFileStorePtr != nil {
+			if err := vialDestroy(i.valueFileStorePtr); err != nil {
+				errs.Errors = append(errs.Errors, fmt.Errorf("destroy *github.com/GarrickZ2/vial/cmd/vial/testdata/app.FileStore failed: %w", err))
+			}
+		}
+		var zero *FileStore
+		i.valueFileStorePtr, i.doneFileStorePtr = zero, false
+	}
+	i.lockFileStorePtr.Unlock()
+	i.lockConfigPtr.Lock()
+	if i.doneConfigPtr {
+		if i.valueConfigPtr != nil {
+			if err := vialDestroy(i.valueConfigPtr); err != nil {
+				errs.Errors = append(errs.Errors, fmt.Errorf("destroy *github.com/GarrickZ2/vial/cmd/vial/testdata/app.Config failed: %w", err))
+			}
+		}
+		var zero *Config
+		i.valueConfigPtr, i.doneConfigPtr = zero, false
+	}
+	i.lockConfigPtr.Unlock()
+	if len(errs.Errors) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func vialInit(bean interface{}) error {
+	if initializer, ok := bean.(vial.Initializer); ok {
+		return initializer.Init()
+	}
+	return nil
+}
+
+func vialDestroy(bean interface{}) error {
+	if destroyer, ok := bean.(vial.Destroyer); ok {
+		return destroyer.Destroy()
+	}
+	if closer, ok := bean.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
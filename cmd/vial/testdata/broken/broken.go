@@ -0,0 +1,43 @@
+package broken
+
+import (
+	"github.com/GarrickZ2/vial"
+)
+
+type A struct {
+	B *B `auto_wire:""`
+}
+
+type B struct {
+	A *A `auto_wire:""`
+}
+
+type C struct{}
+
+func Cycle() {
+	vial.RegisterStruct[*A]()
+	vial.RegisterStruct[*B]()
+}
+
+func Scoped() {
+	vial.RegisterStruct[*C](vial.WithRequestScope())
+	vial.RegisterStruct[*C]()
+}
+
+type D struct {
+	C *C `auto_wire:"" qualifier:"replica"`
+}
+
+func Qualified() {
+	vial.RegisterStruct[*C]()
+	vial.RegisterStruct[*D]()
+}
+
+type Service interface{}
+
+func Containers() {
+	ctr := vial.NewContainer()
+	ctr.RegisterStructByInstance(&C{})
+	ctr.Bind(new(Service), &C{})
+	vial.RegisterStructToContainer[*D](ctr)
+}
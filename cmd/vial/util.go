@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func getConcreteType(data types.Type) (types.Type, int) {
+	level := 0
+	for {
+		pointer, ok := data.(*types.Pointer)
+		if !ok {
+			return data, level
+		}
+		data = pointer.Elem()
+		level++
+	}
+}
+
+func typeName(data types.Type) string {
+	switch t := data.(type) {
+	case *types.Named:
+		return t.Obj().Name()
+	case *types.Alias:
+		return t.Obj().Name()
+	case *types.Basic:
+		return t.Name()
+	}
+	return ""
+}
+
+// getQualifiedClassName returns the same name as vial does with reflect
+func getQualifiedClassName(data types.Type) string {
+	data, level := getConcreteType(data)
+	pkgPath := ""
+	if named, ok := types.Unalias(data).(*types.Named); ok && named.Obj().Pkg() != nil {
+		pkgPath = named.Obj().Pkg().Path()
+	}
+	return strings.Repeat("*", level) + pkgPath + "." + typeName(types.Unalias(data))
+}
+
+func getKindType(dataType types.Type) kindType {
+	switch t := dataType.Underlying().(type) {
+	case *types.Slice:
+		if types.IsInterface(t.Elem()) {
+			return interfaceSliceKind
+		}
+	case *types.Map:
+		if basic, ok := t.Key().Underlying().(*types.Basic); ok && basic.Kind() == types.String && types.IsInterface(t.Elem()) {
+			return interfaceMapKind
+		}
+	}
+	dataType, _ = getConcreteType(dataType)
+	if types.IsInterface(dataType) {
+		return interfaceKind
+	}
+	return structKind
+}
+
+func sortedKeys[V any](data map[string]V) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueLiteral validates the value tag like vial does, and returns the Go expression of the value
+func valueLiteral(fieldType types.Type, value string) (string, error) {
+	concreteType, level := getConcreteType(fieldType)
+	basic, ok := concreteType.(*types.Basic)
+	// the converters are resolved at runtime, only the original data types are compiled
+	if _, named := concreteType.(*types.Named); named || !ok {
+		return "", fmt.Errorf("value of type %v cannot be compiled into static wiring", types.TypeString(fieldType, nil))
+	}
+	var literal string
+	switch basic.Kind() {
+	case types.String:
+		literal = strconv.Quote(value)
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		bits := map[types.BasicKind]int{types.Int: 64, types.Int8: 8, types.Int16: 16, types.Int32: 32, types.Int64: 64}[basic.Kind()]
+		res, err := strconv.ParseInt(value, 10, bits)
+		if err != nil {
+			return "", err
+		}
+		literal = fmt.Sprintf("%v(%d)", basic.Name(), res)
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		bits := map[types.BasicKind]int{types.Uint: 64, types.Uint8: 8, types.Uint16: 16, types.Uint32: 32, types.Uint64: 64}[basic.Kind()]
+		res, err := strconv.ParseUint(value, 10, bits)
+		if err != nil {
+			return "", err
+		}
+		literal = fmt.Sprintf("%v(%d)", basic.Name(), res)
+	case types.Bool:
+		res, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		literal = strconv.FormatBool(res)
+	case types.Float32, types.Float64:
+		bits := 64
+		if basic.Kind() == types.Float32 {
+			bits = 32
+		}
+		res, err := strconv.ParseFloat(value, bits)
+		if err != nil {
+			return "", err
+		}
+		literal = fmt.Sprintf("%v(%v)", basic.Name(), strconv.FormatFloat(res, 'g', -1, bits))
+	case types.Complex64, types.Complex128:
+		bits := 128
+		if basic.Kind() == types.Complex64 {
+			bits = 64
+		}
+		res, err := strconv.ParseComplex(value, bits)
+		if err != nil {
+			return "", err
+		}
+		literal = fmt.Sprintf("%v(complex(%v, %v))", basic.Name(),
+			strconv.FormatFloat(real(res), 'g', -1, bits/2), strconv.FormatFloat(imag(res), 'g', -1, bits/2))
+	default:
+		return "", fmt.Errorf("data type %v cannot use value tag", basic.Name())
+	}
+	// the value of pointer fields is allocated like vial.newValue
+	pointers := make([]types.Type, 0, level)
+	for current := fieldType; len(pointers) < level; current = current.(*types.Pointer).Elem() {
+		pointers = append(pointers, current)
+	}
+	for i := level - 1; i >= 0; i-- {
+		literal = fmt.Sprintf("func() %v { v := %v; return &v }()", types.TypeString(pointers[i], nil), literal)
+	}
+	return literal, nil
+}
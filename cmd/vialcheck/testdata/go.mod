@@ -0,0 +1,7 @@
+module example.com/vialcheck
+
+go 1.22.0
+
+require github.com/GarrickZ2/vial v0.0.0-00010101000000-000000000000
+
+replace github.com/GarrickZ2/vial => ../../../
@@ -0,0 +1,67 @@
+package tags
+
+import (
+	"net"
+	"time"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type Level int
+
+type Store interface {
+	Load() string
+}
+
+type MemoryStore struct{}
+
+func (m *MemoryStore) Load() string {
+	return ""
+}
+
+type Valid struct {
+	Store   Store             `auto_wire:"" qualifier:"memory"`
+	Stores  []Store           `auto_wire:""`
+	Cache   *MemoryStore      `optional:""`
+	Name    string            `value:"vial"`
+	Port    *int              `value:"8080"`
+	Ratio   float32           `value:"0.5"`
+	DBPort  int               `value:"${db.port}"`
+	DBHost  string            `value:"${db.host:localhost}:${db.port:5432}"`
+	Timeout time.Duration     `value:"5s"`
+	Since   time.Time         `value:"2024-01-02"`
+	Hosts   []string          `value:"a,b"`
+	Weights map[string]int    `value:"read=1, write=2"`
+	Level   Level             `value:"debug"`
+	IP      net.IP            `value:"127.0.0.1"`
+	Limit   vial.Dynamic[int] `value:"${limit:10}"`
+	Other   string            `json:"other" values:"a,b"`
+	private int
+}
+
+type Invalid struct {
+	store   Store             `auto_wire:""`                   // want `field store is unexported, cannot set as auto-wired`
+	Port    int               `value:"abc"`                    // want `Parsing Value Tag Error: strconv.ParseInt: parsing "abc": invalid syntax`
+	Small   int8              `value:"300"`                    // want `Parsing Value Tag Error: strconv.ParseInt: parsing "300": value out of range`
+	Retry   int               `value:"${retry:many}"`          // want `Parsing Value Tag Error: strconv.ParseInt: parsing "many": invalid syntax`
+	Timeout time.Duration     `value:"5x"`                     // want `Parsing Value Tag Error: time: unknown unit "x" in duration "5x"`
+	Events  chan int          `value:"1"`                      // want `Parsing Value Tag Error: data type chan int cannot use value tag, register a converter for it`
+	Weights map[string]int    `value:"read"`                   // want `Parsing Value Tag Error: map item "read" should be k=v`
+	Names   []int             `value:"1,x"`                    // want `Parsing Value Tag Error: strconv.ParseInt: parsing "x": invalid syntax`
+	Limit   vial.Dynamic[int] `value:"ten"`                    // want `Parsing Value Tag Error: strconv.ParseInt: parsing "ten": invalid syntax`
+	Backup  Store             `qualifier:"memory"`             // want `qualifier of field Backup has no effect without auto_wire`
+	Typo    Store             `autowire:""`                    // want `tag "autowire" of field Typo looks like a misspelling of "auto_wire", vial ignores it`
+	Typo2   Store             `auto_wire:"" qualifer:"memory"` // want `tag "qualifer" of field Typo2 looks like a misspelling of "qualifier", vial ignores it`
+	Typo3   *Valid            `Optional:""`                    // want `tag "Optional" of field Typo3 looks like a misspelling of "optional", vial ignores it`
+}
+
+func register(ctr *vial.Container, dynamic interface{}) {
+	vial.Bind[Store, *MemoryStore]()
+	vial.Bind[Store, MemoryStore]()         // want `The primary struct type MemoryStore not implement the interface Store`
+	vial.Bind[Store, *MemoryStore](Valid{}) // want `The struct type Valid not implement the interface Store`
+	vial.BindToContainer[Store, *MemoryStore](ctr, &MemoryStore{}, vial.WithProfile("test"))
+	vial.BindByInstance(new(Store), &Valid{}) // want `The primary struct type \*Valid not implement the interface Store`
+	vial.BindByInstance(new(Valid), &Valid{}) // want `Input type Valid is not an interface`
+	ctr.Bind(new(Store), &MemoryStore{}, dynamic)
+	_ = ctr.TryBind(new(Store), dynamic, MemoryStore{}) // want `The struct type MemoryStore not implement the interface Store`
+}
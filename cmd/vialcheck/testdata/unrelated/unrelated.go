@@ -0,0 +1,9 @@
+package unrelated
+
+// the tags look like the vial ones, but the package doesn't use vial
+
+type Settings struct {
+	Port    int    `value:"abc"`
+	Name    string `optinal:""`
+	timeout int    `optional:""`
+}
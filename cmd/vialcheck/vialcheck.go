@@ -0,0 +1,409 @@
+// Package vialcheck defines an Analyzer that reports the mistakes in vial struct tags and Bind calls,
+// which would otherwise panic at startup.
+package vialcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const vialPath = "github.com/GarrickZ2/vial"
+
+const (
+	autoWire  = "auto_wire"
+	qualifier = "qualifier"
+	value     = "value"
+	optional  = "optional"
+)
+
+var placeholderDefault = regexp.MustCompile(`^\$\{[^:${}]+:([^${}]*)\}$`)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "vialcheck",
+	Doc:      "check vial struct tags and Bind calls\n\nvialcheck applies the rules of vial.RegisterStruct and vial.Bind at compile time.",
+	URL:      "https://pkg.go.dev/github.com/GarrickZ2/vial/cmd/vialcheck",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	// the tags in the packages without vial belong to other libraries
+	if !importsVial(pass.Pkg) {
+		return nil, nil
+	}
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.StructType)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(node ast.Node) {
+		switch n := node.(type) {
+		case *ast.StructType:
+			checkStruct(pass, n)
+		case *ast.CallExpr:
+			checkBind(pass, n)
+		}
+	})
+	return nil, nil
+}
+
+func importsVial(pkg *types.Package) bool {
+	for _, each := range pkg.Imports() {
+		if each.Path() == vialPath {
+			return true
+		}
+	}
+	return false
+}
+
+func checkStruct(pass *analysis.Pass, node *ast.StructType) {
+	structure, ok := pass.TypesInfo.TypeOf(node).(*types.Struct)
+	if !ok {
+		return
+	}
+	index := 0
+	for _, field := range node.Fields.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		if field.Tag != nil {
+			for i := index; i < index+count; i++ {
+				checkField(pass, field, structure.Field(i), reflect.StructTag(structure.Tag(i)))
+			}
+		}
+		index += count
+	}
+}
+
+func checkField(pass *analysis.Pass, node *ast.Field, field *types.Var, tag reflect.StructTag) {
+	for _, key := range tagKeys(string(tag)) {
+		if known := misspelledKey(key); known != "" {
+			pass.Reportf(node.Tag.Pos(), "tag %q of field %v looks like a misspelling of %q, vial ignores it", key, field.Name(), known)
+		}
+	}
+
+	val, hasValue := tag.Lookup(value)
+	_, hasAutoWire := tag.Lookup(autoWire)
+	_, hasOptional := tag.Lookup(optional)
+	_, hasQualifier := tag.Lookup(qualifier)
+	injected := hasAutoWire || hasOptional
+
+	if (hasValue || injected) && !field.Exported() {
+		pass.Reportf(node.Pos(), "field %v is unexported, cannot set as auto-wired", field.Name())
+		return
+	}
+	if hasValue {
+		// the placeholders are resolved at runtime, only the default of a single placeholder can be checked
+		if strings.Contains(val, "${") {
+			match := placeholderDefault.FindStringSubmatch(val)
+			if match == nil {
+				return
+			}
+			val = match[1]
+		}
+		if err := validateDefaultValue(field.Type(), val); err != nil {
+			pass.Reportf(node.Tag.Pos(), "Parsing Value Tag Error: %v", err)
+		}
+		return
+	}
+	if hasQualifier && !injected {
+		pass.Reportf(node.Tag.Pos(), "qualifier of field %v has no effect without auto_wire", field.Name())
+	}
+}
+
+// tagKeys returns all the keys in the conventional struct tag, like reflect.StructTag.Lookup walks them
+func tagKeys(tag string) []string {
+	var keys []string
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		keys = append(keys, tag[:i])
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		tag = tag[i+1:]
+	}
+	return keys
+}
+
+// misspelledKey returns the vial tag the key is close to, or empty if it is not a misspelling
+func misspelledKey(key string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+	for _, known := range []string{autoWire, qualifier, value, optional} {
+		if key == known {
+			return ""
+		}
+	}
+	for _, known := range []string{autoWire, qualifier, value, optional} {
+		if normalize(key) == normalize(known) {
+			return known
+		}
+		// value is too short to guess, values or val could be the tag of other libraries
+		if known != value && editDistance(normalize(key), normalize(known)) <= 1 {
+			return known
+		}
+	}
+	return ""
+}
+
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(prev[j]+1, minInt(current[j-1]+1, prev[j-1]+cost))
+		}
+		prev = current
+	}
+	return prev[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// validateDefaultValue applies the same rules as vial does when parsing the value tag. The named types
+// may have a converter registered at runtime, so only time.Duration and time.Time are checked among them.
+func validateDefaultValue(fieldType types.Type, val string) error {
+	for {
+		pointer, ok := fieldType.(*types.Pointer)
+		if !ok {
+			break
+		}
+		fieldType = pointer.Elem()
+	}
+	if named, ok := types.Unalias(fieldType).(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == vialPath && obj.Name() == "Dynamic" && named.TypeArgs().Len() == 1 {
+			return validateDefaultValue(named.TypeArgs().At(0), val)
+		}
+		if obj.Pkg() == nil || obj.Pkg().Path() != "time" {
+			return nil
+		}
+		switch obj.Name() {
+		case "Duration":
+			_, err := time.ParseDuration(val)
+			return err
+		case "Time":
+			for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+				if _, err := time.Parse(layout, val); err == nil {
+					return nil
+				}
+			}
+			return fmt.Errorf("cannot parse %q as time, expect RFC 3339 or 2006-01-02", val)
+		}
+		return nil
+	}
+	var err error
+	switch data := fieldType.Underlying().(type) {
+	case *types.Slice:
+		for _, each := range splitItems(val) {
+			if err = validateDefaultValue(data.Elem(), each); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *types.Map:
+		for _, each := range splitItems(val) {
+			key, item, found := strings.Cut(each, "=")
+			if !found {
+				return fmt.Errorf("map item %q should be k=v", each)
+			}
+			if err = validateDefaultValue(data.Key(), strings.TrimSpace(key)); err != nil {
+				return err
+			}
+			if err = validateDefaultValue(data.Elem(), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *types.Basic:
+		switch data.Kind() {
+		case types.String:
+		case types.Int, types.Int64:
+			_, err = strconv.ParseInt(val, 10, 64)
+		case types.Int8:
+			_, err = strconv.ParseInt(val, 10, 8)
+		case types.Int16:
+			_, err = strconv.ParseInt(val, 10, 16)
+		case types.Int32:
+			_, err = strconv.ParseInt(val, 10, 32)
+		case types.Uint, types.Uint64, types.Uintptr:
+			_, err = strconv.ParseUint(val, 10, 64)
+		case types.Uint8:
+			_, err = strconv.ParseUint(val, 10, 8)
+		case types.Uint16:
+			_, err = strconv.ParseUint(val, 10, 16)
+		case types.Uint32:
+			_, err = strconv.ParseUint(val, 10, 32)
+		case types.Bool:
+			_, err = strconv.ParseBool(val)
+		case types.Float32:
+			_, err = strconv.ParseFloat(val, 32)
+		case types.Float64:
+			_, err = strconv.ParseFloat(val, 64)
+		case types.Complex64:
+			_, err = strconv.ParseComplex(val, 64)
+		case types.Complex128:
+			_, err = strconv.ParseComplex(val, 128)
+		default:
+			return fmt.Errorf("data type %v cannot use value tag, register a converter for it", fieldType)
+		}
+		return err
+	default:
+		return fmt.Errorf("data type %v cannot use value tag, register a converter for it", fieldType)
+	}
+}
+
+func splitItems(text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	items := strings.Split(text, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// checkBind reports the Bind calls whose structs don't implement the interface
+func checkBind(pass *analysis.Pass, call *ast.CallExpr) {
+	name, typeArgs, method := vialFunc(pass, call.Fun)
+	var interfaceType types.Type
+	var structs []ast.Expr
+	var structTypes []types.Type
+	switch {
+	case !method && (name == "Bind" || name == "TryBind") && len(typeArgs) == 2:
+		interfaceType, structTypes = typeArgs[0], []types.Type{typeArgs[1]}
+		structs = append([]ast.Expr{call.Fun}, call.Args...)
+	case !method && (name == "BindToContainer" || name == "TryBindToContainer") && len(typeArgs) == 2 && len(call.Args) > 0:
+		interfaceType, structTypes = typeArgs[0], []types.Type{typeArgs[1]}
+		structs = append([]ast.Expr{call.Fun}, call.Args[1:]...)
+	case (name == "BindByInstance" || name == "TryBindByInstance" || (method && (name == "Bind" || name == "TryBind"))) && len(call.Args) >= 2:
+		interfaceType = pass.TypesInfo.TypeOf(call.Args[0])
+		if pointer, ok := interfaceType.(*types.Pointer); ok {
+			interfaceType = pointer.Elem()
+		}
+		structs = call.Args[1:]
+	default:
+		return
+	}
+	for _, each := range structs[len(structTypes):] {
+		structTypes = append(structTypes, pass.TypesInfo.TypeOf(each))
+	}
+
+	qualifier := types.RelativeTo(pass.Pkg)
+	if isDynamic(interfaceType) {
+		return
+	}
+	iface, ok := interfaceType.Underlying().(*types.Interface)
+	if !ok {
+		pass.Reportf(call.Pos(), "Input type %v is not an interface", types.TypeString(interfaceType, qualifier))
+		return
+	}
+	for i, each := range structTypes {
+		if each == nil || isDynamic(each) || isOption(each) {
+			continue
+		}
+		if !types.Implements(each, iface) {
+			kind := "struct"
+			if i == 0 {
+				kind = "primary struct"
+			}
+			pass.Reportf(structs[i].Pos(), "The %v type %v not implement the interface %v",
+				kind, types.TypeString(each, qualifier), types.TypeString(interfaceType, qualifier))
+		}
+	}
+}
+
+// isDynamic checks the type is only known at runtime, e.g. an interface{} argument
+func isDynamic(t types.Type) bool {
+	if t == nil {
+		return true
+	}
+	if basic, ok := t.(*types.Basic); ok && basic.Kind() == types.UntypedNil {
+		return true
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	return ok && iface.Empty()
+}
+
+// isOption checks the type is the options of vial, which can be passed to Bind among the structs
+func isOption(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == vialPath && named.Obj().Name() == "applyOption"
+}
+
+// vialFunc returns the vial function or Container method called by the expression, with its type arguments
+func vialFunc(pass *analysis.Pass, expr ast.Expr) (string, []types.Type, bool) {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(expr).(type) {
+	case *ast.IndexExpr:
+		return vialFunc(pass, fun.X)
+	case *ast.IndexListExpr:
+		return vialFunc(pass, fun.X)
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return "", nil, false
+	}
+	fn, ok := pass.TypesInfo.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != vialPath {
+		return "", nil, false
+	}
+	var typeArgs []types.Type
+	if instance, found := pass.TypesInfo.Instances[ident]; found {
+		for i := 0; i < instance.TypeArgs.Len(); i++ {
+			typeArgs = append(typeArgs, instance.TypeArgs.At(i))
+		}
+	}
+	method := fn.Type().(*types.Signature).Recv() != nil
+	return fn.Name(), typeArgs, method
+}
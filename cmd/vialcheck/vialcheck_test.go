@@ -0,0 +1,11 @@
+package vialcheck
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, "testdata", Analyzer, "./tags", "./unrelated")
+}
@@ -0,0 +1,14 @@
+// Command vialvet runs the vialcheck analyzer with go vet:
+//
+//	go install github.com/GarrickZ2/vial/cmd/vialvet@latest
+//	go vet -vettool=$(which vialvet) ./...
+package main
+
+import (
+	"github.com/GarrickZ2/vial/cmd/vialcheck"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(vialcheck.Analyzer)
+}
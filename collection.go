@@ -1,84 +1,323 @@
 package vial
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 type singletonEntry struct {
-	assigned bool
 	metaInfo *structMetaInfo
-	value    interface{}
-	lock     sync.Mutex
+	// created holds the *createdValue of the singleton, it's read without the lock
+	created atomic.Value
+	lock    sync.Mutex
 }
 
-func (s *singletonEntry) GetValue() (interface{}, error) {
-	if s.assigned {
-		return s.value, nil
+type createdValue struct {
+	value interface{}
+}
+
+func newSingletonEntry(metaInfo *structMetaInfo) *singletonEntry {
+	entry := &singletonEntry{metaInfo: metaInfo}
+	if metaInfo.prebuilt() {
+		entry.set(metaInfo.instance)
+	}
+	return entry
+}
+
+func (s *singletonEntry) load() *createdValue {
+	created, _ := s.created.Load().(*createdValue)
+	return created
+}
+
+func (s *singletonEntry) set(value interface{}) {
+	s.created.Store(&createdValue{value})
+}
+
+func (s *singletonEntry) GetValue(build func() (interface{}, error)) (interface{}, error) {
+	if created := s.load(); created != nil {
+		return created.value, nil
 	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	if s.assigned {
-		return s.value, nil
+	if created := s.load(); created != nil {
+		return created.value, nil
 	}
-	result, err := c.buildStruct(s.metaInfo)
+	result, err := build()
 	if err != nil {
 		return nil, err
 	}
-	s.assigned = true
-	s.value = result
+	s.set(result)
 	return result, nil
 }
 
+// current returns the singleton if it's created
+func (s *singletonEntry) current() interface{} {
+	if created := s.load(); created != nil {
+		return created.value
+	}
+	return nil
+}
+
+func (s *singletonEntry) destroy() error {
+	s.lock.Lock()
+	created := s.load()
+	if created == nil {
+		s.lock.Unlock()
+		return nil
+	}
+	s.created.Store((*createdValue)(nil))
+	s.lock.Unlock()
+	return runDestroyHook(s.metaInfo, created.value)
+}
+
+// collection is the state read by Get after Done: the checked registrations, the singletons and the scopes.
+// It's never changed, Override and Restore publish a new one, so a build reads the same state all the way.
 type collection struct {
-	initType     int
+	register     *register
 	singletonMap map[string]*singletonEntry
+	scopes       map[string]Scope
+	// the container owning the collection builds the singletons, and its parent builds the missing structs
+	container *Container
+	parent    *Container
+}
+
+func newCollection(container *Container, r *register) *collection {
+	l := &collection{
+		register:     r,
+		singletonMap: make(map[string]*singletonEntry),
+		scopes:       make(map[string]Scope, len(container.scopes)),
+		container:    container,
+		parent:       container.parent,
+	}
+	for name, each := range r.sMap {
+		if each.option.scope == singleton {
+			l.singletonMap[name] = newSingletonEntry(each)
+		}
+	}
+	for name, scope := range container.scopes {
+		l.scopes[name] = scope
+	}
+	return l
+}
+
+// keep reuses the singletons of the old collection which are not replaced
+func (l *collection) keep(old *collection, replaced map[string]bool) {
+	for name := range l.singletonMap {
+		if entry, ok := old.singletonMap[name]; ok && !replaced[name] {
+			l.singletonMap[name] = entry
+		}
+	}
+}
+
+// changed returns the structs which are not shared with the old collection, they're all changed if it's nil
+func (l *collection) changed(old *collection) map[string]bool {
+	result := make(map[string]bool, len(l.register.sMap))
+	for name, meta := range l.register.sMap {
+		if old == nil || old.register.sMap[name] == nil || meta.option.scope != singleton ||
+			l.singletonMap[name] != old.singletonMap[name] {
+			result[name] = true
+		}
+	}
+	if old != nil {
+		for name := range old.register.sMap {
+			if l.register.sMap[name] == nil {
+				result[name] = true
+			}
+		}
+	}
+	return result
+}
+
+// destroyDropped destroys the created singletons of the old collection which are not kept by the current one,
+// the structs holding the others are destroyed first
+func destroyDropped(old *collection, current *collection) {
+	if old == nil {
+		return
+	}
+	for i := len(old.register.order) - 1; i >= 0; i-- {
+		name := old.register.order[i]
+		entry := old.singletonMap[name]
+		if entry == nil || entry.metaInfo.prebuilt() || entry.current() == nil {
+			continue
+		}
+		if current != nil && current.singletonMap[name] == entry {
+			continue
+		}
+		log.Printf("vial: singleton %v is dropped and destroyed", name)
+		if err := entry.destroy(); err != nil {
+			log.Printf("vial: destroy the dropped singleton %v failed: %v", name, err)
+		}
+	}
 }
 
-func newCollection() *collection {
-	return &collection{0, make(map[string]*singletonEntry)}
+// parentCollection returns the current collection of the parent container
+func (l *collection) parentCollection() (*collection, error) {
+	return l.parent.current()
+}
+
+// buildingKey keeps the singletons being built by the current call in the ctx. A singleton met again before
+// it's created means a cycle through Provider or Lazy, which would wait for its own lock forever
+type buildingKey struct{}
+
+type buildingPath struct {
+	entries []*singletonEntry
+	// done is shared by the whole path, and set when the outermost build returns
+	done *int32
+}
+
+func (l *collection) getSingleton(ctx context.Context, name string) (interface{}, error) {
+	entry := l.singletonMap[name]
+	if created := entry.load(); created != nil {
+		return created.value, nil
+	}
+	// a Provider or Lazy used after the build keeps the ctx of the build, the path is finished then
+	path, _ := ctx.Value(buildingKey{}).(*buildingPath)
+	if path == nil || atomic.LoadInt32(path.done) == 1 {
+		path = &buildingPath{done: new(int32)}
+		defer atomic.StoreInt32(path.done, 1)
+	}
+	for i, each := range path.entries {
+		if each == entry {
+			return nil, newBuildingCycle(path.entries[i:])
+		}
+	}
+	next := &buildingPath{entries: append(path.entries[:len(path.entries):len(path.entries)], entry), done: path.done}
+	return entry.GetValue(func() (interface{}, error) {
+		if l.container.isClosed() {
+			return nil, ErrClosed
+		}
+		// singletons are shared by all requests, so they never see the request scope
+		result, err := l.buildStruct(context.WithValue(context.Background(), buildingKey{}, next), entry.metaInfo)
+		if err == nil && l.container.isClosed() {
+			// Close has passed the entry, so destroy it here rather than keeping it forever
+			_ = runDestroyHook(entry.metaInfo, result)
+			return nil, ErrClosed
+		}
+		return result, err
+	})
+}
+
+func newBuildingCycle(entries []*singletonEntry) *CycleError {
+	path := make([]string, 0, len(entries)+1)
+	structs := make(map[string]bool, len(entries))
+	for _, each := range entries {
+		path = append(path, each.metaInfo.name)
+		structs[each.metaInfo.name] = true
+	}
+	return &CycleError{Path: append(path, entries[0].metaInfo.name), Structs: sortedKeys(structs)}
 }
 
 // 1. if the data is an interface => find the binding
 // 2. with the concrete structure, find whether
-func (c *Container) getValue(data interface{}) (interface{}, error) {
+func (l *collection) getValue(ctx context.Context, data interface{}) (interface{}, error) {
 	dataType := reflect.TypeOf(data)
-	return c.buildStructWithSingleton(getQualifiedClassName(dataType), getKindType(dataType))
+	kind := getKindType(dataType)
+	if kind == interfaceSliceKind || kind == interfaceMapKind {
+		result, err := l.buildDependency(ctx, newDependencyInfo(dataType))
+		if err != nil {
+			return nil, err
+		}
+		return result.Interface(), nil
+	}
+	name := getQualifiedClassName(dataType)
+	if meta := l.register.findStruct(name); meta != nil && kind != interfaceKind && len(meta.ambiguous) > 0 {
+		return nil, &AmbiguousBeanError{Name: name, Beans: meta.ambiguous}
+	}
+	return l.buildStructWithSingleton(ctx, name, kind)
+}
+
+// getNamedValue gets the struct by the bean name, the name of an interface is the qualifier of its binding
+func (l *collection) getNamedValue(ctx context.Context, data interface{}, name string) (interface{}, error) {
+	dataType := reflect.TypeOf(data)
+	if dataType == nil {
+		return nil, fmt.Errorf("cannot get a nil type from vial")
+	}
+	if dataType.Kind() == reflect.Pointer && dataType.Elem().Kind() == reflect.Interface {
+		dataType = dataType.Elem()
+	}
+	id := getQualifiedClassName(dataType)
+	if dataType.Kind() == reflect.Interface {
+		bindInfo := l.register.findInterface(id)
+		if bindInfo == nil {
+			return nil, &MissingBindingError{Name: id, Interface: true}
+		}
+		key, ok := bindInfo.nameMapping[name]
+		if !ok {
+			return nil, &QualifierNotFoundError{Qualifier: name, Interface: id}
+		}
+		return l.buildStructWithSingleton(ctx, key, structKind)
+	}
+	key, _, found := l.register.lookupStruct(id, name)
+	if !found {
+		return nil, &MissingBindingError{Name: key}
+	}
+	return l.buildStructWithSingleton(ctx, key, structKind)
 }
 
-func (c *Container) buildStructWithSingleton(name string, kt kindType) (interface{}, error) {
+func (l *collection) buildStructWithSingleton(ctx context.Context, name string, kt kindType) (interface{}, error) {
 	if kt == interfaceKind {
-		iMetaInfo := c.register.iMap[name]
+		iMetaInfo := l.register.iMap[name]
+		if iMetaInfo == nil && l.parent != nil {
+			parent, err := l.parentCollection()
+			if err != nil {
+				return nil, err
+			}
+			return parent.buildStructWithSingleton(ctx, name, kt)
+		}
+		if iMetaInfo == nil {
+			return nil, fmt.Errorf("not find bind information for interface %v", name)
+		}
 		name = iMetaInfo.primary
 	}
-	metaInfo := c.register.sMap[name]
+	metaInfo := l.register.sMap[name]
+	if metaInfo == nil && l.parent != nil {
+		parent, err := l.parentCollection()
+		if err != nil {
+			return nil, err
+		}
+		return parent.buildStructWithSingleton(ctx, name, structKind)
+	}
 	if metaInfo == nil {
 		return nil, fmt.Errorf("not found %v registered in vial", name)
 	}
-	entry := c.collection.singletonMap[metaInfo.name]
-	if entry != nil {
-		return entry.GetValue()
+	build := func() (interface{}, error) {
+		return l.buildStruct(ctx, metaInfo)
+	}
+	switch metaInfo.option.scope {
+	case singleton:
+		return l.getSingleton(ctx, metaInfo.name)
+	case requestScope:
+		scope := l.container.scopeFromContext(ctx)
+		if scope == nil {
+			return nil, fmt.Errorf("%v is request scoped, but no request scope found in the context", metaInfo.name)
+		}
+		return scope.getValue(l.container, metaInfo, build)
+	case customScope:
+		return l.scopes[metaInfo.option.scopeName].Get(ctx, metaInfo.name, build)
+	default:
+		return build()
 	}
-	return c.buildStruct(metaInfo)
 }
 
-func (c *Container) buildStruct(meta *structMetaInfo) (interface{}, error) {
+func (l *collection) buildStruct(ctx context.Context, meta *structMetaInfo) (interface{}, error) {
+	if meta.prebuilt() {
+		return meta.instance, nil
+	}
 	valueList := make([]reflect.Value, 0, len(meta.dependency))
 	for _, each := range meta.dependency {
-		if each.kind == valueKind {
-			valueList = append(valueList, each.value)
-		} else {
-			buildResult, err := c.buildStructWithSingleton(each.reference, structKind)
-			if err != nil {
-				return nil, err
-			}
-			valueList = append(valueList, reflect.ValueOf(buildResult))
+		value, err := l.buildDependency(ctx, each)
+		if err != nil {
+			return nil, err
 		}
+		valueList = append(valueList, value)
 	}
 	if meta.buildType == buildByInject {
 		returnResult := newValueByInject(meta.originType, valueList)
-		return returnResult.Interface(), nil
+		return runInitHook(meta, returnResult.Interface())
 	} else if meta.buildType == buildByConstructor {
 		result := meta.constructor.Call(valueList)
 		if len(result) == 2 {
@@ -86,7 +325,103 @@ func (c *Container) buildStruct(meta *structMetaInfo) (interface{}, error) {
 				return nil, result[1].Interface().(error)
 			}
 		}
-		return result[0].Interface(), nil
+		return runInitHook(meta, result[0].Interface())
 	}
 	return nil, fmt.Errorf("internal error, unknown build type")
 }
+
+func (l *collection) buildDependency(ctx context.Context, info *dependencyInfo) (reflect.Value, error) {
+	if info.absent {
+		if info.wrapper != nil {
+			return reflect.Zero(info.wrapper), nil
+		}
+		return reflect.Zero(info.dataType), nil
+	}
+	if info.wrapper == nil {
+		return l.resolveDependency(ctx, info)
+	}
+	resolve := func(from *collection) (interface{}, error) {
+		result, err := from.resolveDependency(ctx, info)
+		if err != nil || !result.IsValid() {
+			return nil, err
+		}
+		return result.Interface(), nil
+	}
+	switch wrapper := reflect.Zero(info.wrapper).Interface().(type) {
+	case dependencyWrapper:
+		// Provider and Lazy may be used after Override or Restore, so they resolve from the current collection
+		return reflect.ValueOf(wrapper.wrap(func() (interface{}, error) {
+			current, err := l.container.current()
+			if err != nil {
+				return nil, err
+			}
+			return resolve(current)
+		})), nil
+	case optionalWrapper:
+		result, err := resolve(l)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(wrapper.of(result)), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("internal error, unknown wrapper type %v", info.wrapper)
+	}
+}
+
+func (l *collection) resolveDependency(ctx context.Context, info *dependencyInfo) (reflect.Value, error) {
+	if info.inherited {
+		parent, err := l.parentCollection()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return parent.resolveTarget(ctx, info)
+	}
+	return l.resolveTarget(ctx, info)
+}
+
+// resolveTarget builds the dependency by the container, the missing ones are built by the parent
+func (l *collection) resolveTarget(ctx context.Context, info *dependencyInfo) (reflect.Value, error) {
+	switch info.kind {
+	case valueKind:
+		// every instance gets its own slices, maps and pointers, the Dynamic cells are still shared
+		return deepCopy(info.value), nil
+	case interfaceSliceKind, interfaceMapKind:
+		bindInfo := l.register.iMap[info.name]
+		if bindInfo == nil && l.parent != nil {
+			parent, err := l.parentCollection()
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return parent.resolveTarget(ctx, info)
+		}
+		if bindInfo == nil {
+			return reflect.Value{}, fmt.Errorf("not find bind information for interface %v", info.name)
+		}
+		if info.kind == interfaceSliceKind {
+			result := reflect.MakeSlice(info.dataType, 0, len(bindInfo.others))
+			for _, each := range bindInfo.implementations() {
+				buildResult, err := l.buildStructWithSingleton(ctx, each, structKind)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				result = reflect.Append(result, reflect.ValueOf(buildResult))
+			}
+			return result, nil
+		}
+		result := reflect.MakeMapWithSize(info.dataType, len(bindInfo.nameMapping))
+		for name, each := range bindInfo.nameMapping {
+			buildResult, err := l.buildStructWithSingleton(ctx, each, structKind)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.SetMapIndex(reflect.ValueOf(name).Convert(info.dataType.Key()), reflect.ValueOf(buildResult))
+		}
+		return result, nil
+	default:
+		buildResult, err := l.buildStructWithSingleton(ctx, info.reference, structKind)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(buildResult), nil
+	}
+}
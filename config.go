@@ -1,14 +1,40 @@
 package vial
 
+import "reflect"
+
 type option struct {
-	scope scope
-	name  string
+	scope         scope
+	scopeName     string
+	name          string
+	initMethod    string
+	destroyMethod string
+	// the bean is registered only if all the conditions pass
+	conditions []func(env Env) bool
+	// the bean is dropped if another one is registered with the same id
+	isDefault bool
+	// the qualifiers of the constructor params
+	params []string
+	// the bean is injected without a qualifier when several beans of the type are registered
+	primary bool
 }
 
 func newDefaultOption() option {
 	return option{scope: singleton}
 }
 
+func (o option) scopeString() string {
+	switch o.scope {
+	case protoType:
+		return "prototype"
+	case requestScope:
+		return "request"
+	case customScope:
+		return o.scopeName
+	default:
+		return "singleton"
+	}
+}
+
 type applyOption struct {
 	apply func(config *option)
 }
@@ -25,8 +51,92 @@ func WithProtoType() applyOption {
 	}}
 }
 
+func WithRequestScope() applyOption {
+	return applyOption{func(config *option) {
+		config.scope = requestScope
+	}}
+}
+
+// WithScope selects a scope by name, the custom scope should be registered by Container.RegisterScope
+func WithScope(name string) applyOption {
+	return applyOption{func(config *option) {
+		switch name {
+		case "singleton":
+			config.scope = singleton
+		case "prototype":
+			config.scope = protoType
+		case "request":
+			config.scope = requestScope
+		default:
+			config.scope = customScope
+			config.scopeName = name
+		}
+	}}
+}
+
 func WithName(name string) applyOption {
 	return applyOption{func(config *option) {
 		config.name = name
 	}}
 }
+
+// Named qualifies the constructor param by the bean name, the markers are applied to the params in order,
+// and Named("") skips a param
+func Named(name string) applyOption {
+	return applyOption{func(config *option) {
+		config.params = append(config.params, name)
+	}}
+}
+
+// WithPrimary marks the bean injected without a qualifier, when several beans of the same type are registered
+func WithPrimary() applyOption {
+	return applyOption{func(config *option) {
+		config.primary = true
+	}}
+}
+
+// NamedStruct refers to the bean of the type with the bean name, it can be given to Bind as the struct bound
+// and to Override as the target
+func NamedStruct[T any](name string) interface{} {
+	return namedStruct{reflect.TypeOf((*T)(nil)).Elem(), name}
+}
+
+type namedStruct struct {
+	dataType reflect.Type
+	name     string
+}
+
+func WithInitMethod(method string) applyOption {
+	return applyOption{func(config *option) {
+		config.initMethod = method
+	}}
+}
+
+func WithDestroyMethod(method string) applyOption {
+	return applyOption{func(config *option) {
+		config.destroyMethod = method
+	}}
+}
+
+// WithProfile registers the bean or the binding only if any of the profiles is active, a profile starting
+// with ! matches when it's not active
+func WithProfile(profiles ...string) applyOption {
+	return WithCondition(func(env Env) bool {
+		return env.AcceptsProfiles(profiles...)
+	})
+}
+
+// WithCondition registers the bean or the binding only if the condition passes, it's checked in Done
+func WithCondition(condition func(env Env) bool) applyOption {
+	return applyOption{func(config *option) {
+		config.conditions = append(config.conditions, condition)
+	}}
+}
+
+// WithDefault registers the bean or the binding as a default, which is dropped in Done if the same struct
+// or interface is registered without WithDefault
+func WithDefault() applyOption {
+	return applyOption{func(config *option) {
+		config.isDefault = true
+	}}
+}
@@ -5,12 +5,16 @@ type scope int
 const (
 	singleton scope = iota
 	protoType
+	requestScope
+	customScope
 )
 
 const (
 	autoWire  string = "auto_wire"
 	qualifier string = "qualifier"
 	value     string = "value"
+	optional  string = "optional"
+	config    string = "config"
 )
 
 type kindType int
@@ -19,6 +23,9 @@ const (
 	valueKind kindType = iota
 	structKind
 	interfaceKind
+	// all the structs bound to the interface, injected as []Interface or map[string]Interface
+	interfaceSliceKind
+	interfaceMapKind
 )
 
 type buildType int
@@ -26,4 +33,34 @@ type buildType int
 const (
 	buildByInject buildType = iota
 	buildByConstructor
+	buildByInstance
+	buildByConfig
 )
+
+func (k kindType) String() string {
+	switch k {
+	case valueKind:
+		return "value"
+	case interfaceKind:
+		return "interface"
+	case interfaceSliceKind:
+		return "slice"
+	case interfaceMapKind:
+		return "map"
+	default:
+		return "struct"
+	}
+}
+
+func (b buildType) String() string {
+	switch b {
+	case buildByConstructor:
+		return "constructor"
+	case buildByInstance:
+		return "instance"
+	case buildByConfig:
+		return "config"
+	default:
+		return "inject"
+	}
+}
@@ -1,6 +1,12 @@
 package vial
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
 
 var c *Container
 
@@ -8,61 +14,243 @@ type Container struct {
 	initType   int
 	register   *register
 	collection *collection
+	// lock guards the fields replaced after Done: initType, register, collection and parent
+	lock        sync.RWMutex
+	scopes      map[string]Scope
+	properties  propertySources
+	refreshLock sync.Mutex
+	profiles    []string
+	overrides   []pendingOverride
+	parent      *Container
+	// children are linked to the registrations of the container again when it's overridden or restored
+	children []*Container
+	// closed is set by Close, the singletons are not built any more after it
+	closed int32
 }
 
 func newContainer() *Container {
 	return &Container{
-		register:   newRegister(),
-		collection: newCollection(),
+		register: newRegister(),
+		scopes:   make(map[string]Scope),
 	}
 }
 
-func (c *Container) buildSingletonMap() {
-	singletonMap := make(map[string]*singletonEntry)
-	for name, each := range c.register.sMap {
-		if each.option.scope == singleton {
-			metaInfo := each
-			singletonMap[name] = &singletonEntry{
-				metaInfo: metaInfo,
-			}
-		}
+// current returns the collection read by Get, it's replaced as a whole after Done
+func (c *Container) current() (*collection, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.initType != 1 {
+		return nil, fmt.Errorf("vial hasn't been initialized")
 	}
-	c.collection.singletonMap = singletonMap
+	return c.collection, nil
+}
+
+// publish replaces the collection read by Get, together with its registrations
+func (c *Container) publish(l *collection) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.initType, c.register, c.collection = 1, l.register, l
 }
 
 func (c *Container) RegisterStructByInstance(structType interface{}, options ...applyOption) {
+	if err := c.TryRegisterStructByInstance(structType, options...); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryRegisterStructByInstance(structType interface{}, options ...applyOption) error {
 	if c.initType == 1 {
-		panic("the vial has been initialized, cannot register more")
+		return fmt.Errorf("%w, cannot register more", ErrInitialized)
 	}
-	c.register.RegisterStruct(structType, options...)
+	return c.register.RegisterStruct(structType, options...)
 }
 
 func (c *Container) RegisterConstructor(constructor interface{}, options ...applyOption) {
+	if err := c.TryRegisterConstructor(constructor, options...); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryRegisterConstructor(constructor interface{}, options ...applyOption) error {
+	if c.initType == 1 {
+		return fmt.Errorf("%w, cannot register more", ErrInitialized)
+	}
+	return c.register.RegisterConstruct(constructor, options...)
+}
+
+func (c *Container) RegisterValue(instance interface{}, options ...applyOption) {
+	if err := c.TryRegisterValue(instance, options...); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryRegisterValue(instance interface{}, options ...applyOption) error {
 	if c.initType == 1 {
-		panic("the vial has been initialized, cannot register more")
+		return fmt.Errorf("%w, cannot register more", ErrInitialized)
 	}
-	c.register.RegisterConstruct(constructor, options...)
+	return c.register.RegisterInstance(instance, options...)
 }
 
 func (c *Container) Bind(i interface{}, primaryStruct interface{}, others ...interface{}) {
+	if err := c.TryBind(i, primaryStruct, others...); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryBind(i interface{}, primaryStruct interface{}, others ...interface{}) error {
 	if c.initType == 1 {
-		panic("the vial has been initialized, cannot bind more")
+		return fmt.Errorf("%w, cannot bind more", ErrInitialized)
 	}
-	c.register.Bind(i, primaryStruct, others...)
+	return c.register.Bind(i, primaryStruct, others...)
+}
+
+func (c *Container) RegisterScope(name string, scope Scope) {
+	if err := c.TryRegisterScope(name, scope); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryRegisterScope(name string, scope Scope) error {
+	if c.initType == 1 {
+		return fmt.Errorf("%w, cannot register more", ErrInitialized)
+	}
+	switch name {
+	case "", "singleton", "prototype", "request":
+		return &InvalidDefinitionError{name, fmt.Errorf("scope name %q is reserved", name)}
+	}
+	if _, ok := c.scopes[name]; ok {
+		return &DuplicateRegistrationError{ID: "scope " + name}
+	}
+	c.scopes[name] = scope
+	return nil
+}
+
+// RegisterConfig registers a config struct bound from the properties under the prefix in Done,
+// the field values of the given config are kept if no property is found
+func (c *Container) RegisterConfig(defaults interface{}, prefix string, options ...applyOption) {
+	if err := c.TryRegisterConfig(defaults, prefix, options...); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryRegisterConfig(defaults interface{}, prefix string, options ...applyOption) error {
+	if c.initType == 1 {
+		return fmt.Errorf("%w, cannot register more", ErrInitialized)
+	}
+	return c.register.RegisterConfig(defaults, prefix, options...)
+}
+
+// AddPropertySource appends the source to the property sources, it has lower precedence than the sources added before
+func (c *Container) AddPropertySource(source PropertySource) {
+	c.properties = append(c.properties, source)
+}
+
+// SetPropertySources replaces the property sources, the former source has higher precedence
+func (c *Container) SetPropertySources(sources ...PropertySource) {
+	c.properties = append(propertySources{}, sources...)
 }
 
 func (c *Container) Done() {
+	if err := c.DoneE(); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) DoneE() error {
 	if c.initType == 1 {
-		panic("cannot call Done method twice")
+		return fmt.Errorf("%w, cannot call Done method twice", ErrInitialized)
 	}
-	c.register.ScanAndCheck()
-	c.buildSingletonMap()
-	c.initType = 1
+	// the checks run on a copy, so the registrations are kept if Done fails, and it can be called again
+	cloner := newRegisterCloner()
+	r := cloner.clone(c.register)
+	if err := c.linkParent(r); err != nil {
+		return err
+	}
+	// the errors of all the steps are reported together
+	errs := &MultiError{}
+	errs.add(r.resolveDeferred(c.newEnv()))
+	messages, err := r.applyOverrides(cloner.cloneOverrides(c.overrides))
+	errs.add(err)
+	errs.add(r.ScanAndCheck(c.scopes, c.properties))
+	if err := errs.errorOrNil(); err != nil {
+		return err
+	}
+	c.overrides = nil
+	c.publish(newCollection(c, r))
+	for _, message := range messages {
+		log.Print(message)
+	}
+	return nil
 }
 
 func (c *Container) GetByInstance(dataType interface{}) (interface{}, error) {
-	if c.initType != 1 {
-		return nil, fmt.Errorf("vial hasn't been initialized")
+	return c.GetByInstanceCtx(context.Background(), dataType)
+}
+
+// GetByInstanceCtx resolves the request scoped beans from the RequestScope bound to the ctx by NewScope
+func (c *Container) GetByInstanceCtx(ctx context.Context, dataType interface{}) (interface{}, error) {
+	l, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	if c.isClosed() {
+		return nil, ErrClosed
 	}
-	return c.getValue(dataType)
+	return l.getValue(ctx, dataType)
+}
+
+func (c *Container) GetNamedByInstance(dataType interface{}, name string) (interface{}, error) {
+	return c.GetNamedByInstanceCtx(context.Background(), dataType, name)
+}
+
+// GetNamedByInstanceCtx gets the struct registered with the bean name, or the struct bound to the interface
+// with the bean name. The interface should be given by its pointer, like new(Interface)
+func (c *Container) GetNamedByInstanceCtx(ctx context.Context, dataType interface{}, name string) (interface{}, error) {
+	l, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	if c.isClosed() {
+		return nil, ErrClosed
+	}
+	return l.getNamedValue(ctx, dataType, name)
+}
+
+// Close destroys all created singletons in the reverse order of their dependencies,
+// and returns all the errors happened during destroying. Get returns ErrClosed after it
+func (c *Container) Close(ctx context.Context) error {
+	l, err := c.current()
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.closed, 1)
+	if parent := c.parentContainer(); parent != nil {
+		parent.detach(c)
+	}
+	errs := &MultiError{}
+	order := l.register.order
+	for i := len(order) - 1; i >= 0; i-- {
+		entry := l.singletonMap[order[i]]
+		// the registered instances are managed by the caller, and the configs hold nothing to destroy
+		if entry == nil || entry.metaInfo.prebuilt() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			errs.add(err)
+			break
+		}
+		errs.add(entry.destroy())
+	}
+	return errs.errorOrNil()
+}
+
+// parentContainer returns the parent, it's replaced by Restore
+func (c *Container) parentContainer() *Container {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.parent
+}
+
+func (c *Container) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
 }
@@ -0,0 +1,172 @@
+package vial
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type converterFunc func(text string) (reflect.Value, error)
+
+var (
+	converterLock sync.RWMutex
+	converters    = make(map[reflect.Type]converterFunc)
+
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// RegisterConverter registers how to parse the value tags and properties into the type T.
+// The converters are shared by all containers, and have to be registered before the structs using them.
+func RegisterConverter[T any](convert func(text string) (T, error)) {
+	converterLock.Lock()
+	defer converterLock.Unlock()
+	converters[typeOf[T]()] = func(text string) (reflect.Value, error) {
+		result, err := convert(text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&result).Elem(), nil
+	}
+}
+
+func lookupConverter(dataType reflect.Type) (converterFunc, bool) {
+	converterLock.RLock()
+	defer converterLock.RUnlock()
+	convert, ok := converters[dataType]
+	return convert, ok
+}
+
+// isScalarType checks the type is converted from a single value, instead of split into items or fields
+func isScalarType(dataType reflect.Type) bool {
+	if _, ok := lookupConverter(dataType); ok {
+		return true
+	}
+	if dataType == durationType || dataType == timeType || reflect.PointerTo(dataType).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch dataType.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// convertValue parses the text into the data type, in the order of the registered converters, the built-in
+// time types, encoding.TextUnmarshaler and the kind of the type. A slice is split by commas, and a map is
+// split into k=v pairs.
+func convertValue(dataType reflect.Type, text string) (reflect.Value, error) {
+	if convert, ok := lookupConverter(dataType); ok {
+		return convert(text)
+	}
+	switch {
+	case dataType == durationType:
+		duration, err := time.ParseDuration(text)
+		return reflect.ValueOf(duration), err
+	case dataType == timeType:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if result, err := time.Parse(layout, text); err == nil {
+				return reflect.ValueOf(result), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("cannot parse %q as time, expect RFC 3339 or 2006-01-02", text)
+	case dataType.Kind() == reflect.Pointer:
+		elem, err := convertValue(dataType.Elem(), text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result := reflect.New(dataType.Elem())
+		result.Elem().Set(elem)
+		return result, nil
+	case reflect.PointerTo(dataType).Implements(textUnmarshalerType):
+		result := reflect.New(dataType)
+		if err := result.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return reflect.Value{}, err
+		}
+		return result.Elem(), nil
+	}
+
+	result := reflect.New(dataType).Elem()
+	switch dataType.Kind() {
+	case reflect.String:
+		result.SetString(text)
+	case reflect.Bool:
+		res, err := strconv.ParseBool(text)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetBool(res)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		res, err := strconv.ParseInt(text, 10, dataType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetInt(res)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		res, err := strconv.ParseUint(text, 10, dataType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetUint(res)
+	case reflect.Float32, reflect.Float64:
+		res, err := strconv.ParseFloat(text, dataType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetFloat(res)
+	case reflect.Complex64, reflect.Complex128:
+		res, err := strconv.ParseComplex(text, dataType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetComplex(res)
+	case reflect.Slice:
+		items := splitItems(text)
+		result.Set(reflect.MakeSlice(dataType, 0, len(items)))
+		for _, each := range items {
+			elem, err := convertValue(dataType.Elem(), each)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.Set(reflect.Append(result, elem))
+		}
+	case reflect.Map:
+		result.Set(reflect.MakeMap(dataType))
+		for _, each := range splitItems(text) {
+			key, val, found := strings.Cut(each, "=")
+			if !found {
+				return reflect.Value{}, fmt.Errorf("map item %q should be k=v", each)
+			}
+			mapKey, err := convertValue(dataType.Key(), strings.TrimSpace(key))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			mapValue, err := convertValue(dataType.Elem(), strings.TrimSpace(val))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.SetMapIndex(mapKey, mapValue)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("data type %v cannot use value tag, register a converter for it", dataType)
+	}
+	return result, nil
+}
+
+func splitItems(text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	items := strings.Split(text, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
@@ -0,0 +1,196 @@
+package vial
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// dynamicWrapper is implemented by Dynamic, the value tag is resolved into its target type
+type dynamicWrapper interface {
+	dynamicType() reflect.Type
+	withCell(cell *dynamicCell) interface{}
+}
+
+var dynamicWrapperType = reflect.TypeOf((*dynamicWrapper)(nil)).Elem()
+
+// dynamicBox keeps the stored type the same for atomic.Value, even if the target is an interface
+type dynamicBox struct {
+	value interface{}
+}
+
+// dynamicCell is shared by all the Dynamic fields injected from the same value tag
+type dynamicCell struct {
+	value atomic.Value
+}
+
+func (d *dynamicCell) load() interface{} {
+	if box, ok := d.value.Load().(dynamicBox); ok {
+		return box.value
+	}
+	return nil
+}
+
+func (d *dynamicCell) store(value interface{}) {
+	d.value.Store(dynamicBox{value})
+}
+
+// Dynamic holds a value tag which is updated by Container.Refresh, Get returns the current value atomically
+type Dynamic[T any] struct {
+	cell *dynamicCell
+}
+
+func (d Dynamic[T]) Get() T {
+	var result T
+	if d.cell == nil {
+		return result
+	}
+	if value, ok := d.cell.load().(T); ok {
+		result = value
+	}
+	return result
+}
+
+func (d Dynamic[T]) dynamicType() reflect.Type {
+	return typeOf[T]()
+}
+
+func (d Dynamic[T]) withCell(cell *dynamicCell) interface{} {
+	return Dynamic[T]{cell: cell}
+}
+
+// ConfigChange describes a Dynamic value changed by Container.Refresh
+type ConfigChange struct {
+	Bean     string
+	Field    string
+	Property string
+	Old      interface{}
+	New      interface{}
+}
+
+// ConfigChangeListener is notified after Container.Refresh changes any Dynamic value,
+// only the created singletons are notified
+type ConfigChangeListener interface {
+	OnConfigChange(changes []ConfigChange) error
+}
+
+// newValueInfo creates the dependency of a value tag, a Dynamic field shares a cell between all the built structs
+func newValueInfo(field reflect.StructField) *dependencyInfo {
+	name := getQualifiedClassName(field.Type)
+	info := &dependencyInfo{
+		name:      name,
+		kind:      valueKind,
+		reference: name,
+		dataType:  field.Type,
+		field:     field.Name,
+	}
+	if field.Type.Kind() == reflect.Struct && field.Type.Implements(dynamicWrapperType) {
+		wrapper := reflect.Zero(field.Type).Interface().(dynamicWrapper)
+		info.dynamic = &dynamicCell{}
+		info.dataType = wrapper.dynamicType()
+		info.value = reflect.ValueOf(wrapper.withCell(info.dynamic))
+	}
+	return info
+}
+
+func (d *dependencyInfo) setValue(value reflect.Value) {
+	if d.dynamic != nil {
+		d.dynamic.store(value.Interface())
+		return
+	}
+	d.value = value
+}
+
+// refreshProperties resolves all the value tags with placeholders again, and updates the Dynamic values
+// only if all of them are valid
+func (r *register) refreshProperties(properties propertySources) ([]ConfigChange, error) {
+	type update struct {
+		name  string
+		info  *dependencyInfo
+		value reflect.Value
+	}
+	errs := &MultiError{}
+	updates := make([]update, 0)
+	for _, name := range sortedKeys(r.sMap) {
+		for _, info := range r.sMap[name].dependency {
+			if info.kind != valueKind || info.property == "" {
+				continue
+			}
+			result, err := resolveValue(name, info, properties)
+			if err != nil {
+				errs.add(err)
+				continue
+			}
+			if info.dynamic != nil {
+				updates = append(updates, update{name, info, result})
+			}
+		}
+	}
+	if err := errs.errorOrNil(); err != nil {
+		return nil, err
+	}
+
+	changes := make([]ConfigChange, 0)
+	for _, each := range updates {
+		oldValue, newValue := each.info.dynamic.load(), each.value.Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		each.info.dynamic.store(newValue)
+		changes = append(changes, ConfigChange{
+			Bean:     each.name,
+			Field:    each.info.field,
+			Property: each.info.property,
+			Old:      oldValue,
+			New:      newValue,
+		})
+	}
+	return changes, nil
+}
+
+// Refresh reloads the property sources and updates the Dynamic fields. Nothing is updated if any source cannot
+// be reloaded, or any value tag cannot be resolved or converted. The created singletons which implement
+// ConfigChangeListener are notified in the order of their dependencies.
+func (c *Container) Refresh() error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	l, err := c.current()
+	if err != nil {
+		return err
+	}
+
+	// 1. reload the sources into a new chain, the current one is kept if any source fails
+	properties := make(propertySources, 0, len(c.properties))
+	for _, source := range c.properties {
+		if reloader, ok := source.(PropertyReloader); ok {
+			reloaded, err := reloader.Reload()
+			if err != nil {
+				return err
+			}
+			source = reloaded
+		}
+		properties = append(properties, source)
+	}
+
+	// 2. resolve the value tags by the new chain, and switch to it only if all of them are valid
+	changes, err := l.register.refreshProperties(properties)
+	if err != nil {
+		return err
+	}
+	c.properties = properties
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// 3. notify the listeners
+	errs := &MultiError{}
+	for _, name := range l.register.order {
+		entry := l.singletonMap[name]
+		if entry == nil {
+			continue
+		}
+		if listener, ok := entry.current().(ConfigChangeListener); ok {
+			errs.add(listener.OnConfigChange(changes))
+		}
+	}
+	return errs.errorOrNil()
+}
@@ -0,0 +1,180 @@
+package vial
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MultiError collects several errors which are reported together, e.g. all the wiring problems found by Done
+type MultiError struct {
+	Errors []error
+}
+
+func (m *MultiError) Error() string {
+	if len(m.Errors) == 1 {
+		return m.Errors[0].Error()
+	}
+	var msg strings.Builder
+	msg.WriteString("multiple errors found in vial:")
+	for _, each := range m.Errors {
+		msg.WriteString("\n\t* ")
+		msg.WriteString(strings.ReplaceAll(each.Error(), "\n", "\n\t  "))
+	}
+	return msg.String()
+}
+
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// As finds the first error matching the target, Go before 1.20 doesn't unwrap the errors by Unwrap() []error
+func (m *MultiError) As(target interface{}) bool {
+	for _, each := range m.Errors {
+		if errors.As(each, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MultiError) Is(target error) bool {
+	for _, each := range m.Errors {
+		if errors.Is(each, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MultiError) add(err error) {
+	// the errors gathered by the steps are flattened, so each of them is counted once
+	if multi, ok := err.(*MultiError); ok {
+		m.Errors = append(m.Errors, multi.Errors...)
+	} else if err != nil {
+		m.Errors = append(m.Errors, err)
+	}
+}
+
+func (m *MultiError) errorOrNil() error {
+	if len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+var ErrInitialized = errors.New("the vial has been initialized")
+
+var ErrClosed = errors.New("the vial has been closed")
+
+// DuplicateRegistrationError means a struct is registered twice, or an interface is bound twice
+type DuplicateRegistrationError struct {
+	ID        string
+	Interface bool
+}
+
+func (e *DuplicateRegistrationError) Error() string {
+	if e.Interface {
+		return fmt.Sprintf("Interface %v is already bind", e.ID)
+	}
+	return fmt.Sprintf("Struct %v has been registered already, cannot register twice", e.ID)
+}
+
+// InvalidDefinitionError means the registered struct, constructor or binding is not valid
+type InvalidDefinitionError struct {
+	ID  string
+	Err error
+}
+
+func (e *InvalidDefinitionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidDefinitionError) Unwrap() error {
+	return e.Err
+}
+
+// MissingBindingError means a required struct is not registered, or a required interface is not bound
+type MissingBindingError struct {
+	Name      string
+	Interface bool
+	Path      []string
+}
+
+func (e *MissingBindingError) Error() string {
+	if e.Interface {
+		return fmt.Sprintf("not find bind information for interface %v", e.Name) + formatDependencyPath(e.Path)
+	}
+	return fmt.Sprintf("Not found %v registered in the Vial", e.Name) + formatDependencyPath(e.Path)
+}
+
+// QualifierNotFoundError means no struct bound to the interface has the bean name of the qualifier
+type QualifierNotFoundError struct {
+	Qualifier string
+	Interface string
+	Path      []string
+}
+
+func (e *QualifierNotFoundError) Error() string {
+	return fmt.Sprintf("qualifier %v not found for interface type %v bind", e.Qualifier, e.Interface) + formatDependencyPath(e.Path)
+}
+
+// NameConflictError means two structs bound to the same interface share the same bean name
+type NameConflictError struct {
+	Name      string
+	Interface string
+	Structs   []string
+}
+
+func (e *NameConflictError) Error() string {
+	return fmt.Sprintf("%v and %v share the same name %v for interface bind", e.Structs[0], e.Structs[1], e.Name)
+}
+
+// AmbiguousBeanError means several beans of the type are registered and none of them is marked by WithPrimary,
+// so the bean injected without a qualifier is unknown
+type AmbiguousBeanError struct {
+	Name  string
+	Beans []string
+	Path  []string
+}
+
+func (e *AmbiguousBeanError) Error() string {
+	return fmt.Sprintf("%v has several beans %v, use a qualifier or mark one by WithPrimary", e.Name, strings.Join(e.Beans, ", ")) + formatDependencyPath(e.Path)
+}
+
+// CycleError means a circular dependency is found, Path starts and ends with the same struct. One error is
+// reported for each group of structs relying on each other, Structs lists all of them
+type CycleError struct {
+	Path    []string
+	Structs []string
+	start   string
+}
+
+func (e *CycleError) Error() string {
+	return printCycleInjectionLoop(e.Path)
+}
+
+// ScopeError means a singleton relies on a request scoped or custom scoped bean, which cannot be shared by all requests
+type ScopeError struct {
+	Name       string
+	Dependency string
+	Scope      string
+	Path       []string
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("singleton %v cannot rely on %v scoped %v", e.Name, e.Scope, e.Dependency) + formatDependencyPath(e.Path)
+}
+
+// MissingPropertyError means a placeholder without default refers to a property not found in any PropertySource
+type MissingPropertyError struct {
+	Key string
+	ID  string
+}
+
+func (e *MissingPropertyError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("property %v is not found in the property sources", e.Key)
+	}
+	return fmt.Sprintf("property %v required by %v is not found in the property sources", e.Key, e.ID)
+}
@@ -0,0 +1,193 @@
+package vial
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Graph is the wiring of a container, it's used to render the dependency graph
+type Graph struct {
+	Beans      []GraphBean      `json:"beans"`
+	Interfaces []GraphInterface `json:"interfaces"`
+	Edges      []GraphEdge      `json:"edges"`
+}
+
+type GraphBean struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`
+	BuildType string `json:"buildType"`
+}
+
+type GraphInterface struct {
+	ID      string `json:"id"`
+	Primary string `json:"primary"`
+	// bean name => struct id
+	Implementations map[string]string `json:"implementations"`
+}
+
+// GraphEdge means the bean From relies on the bean To. If the dependency is an interface,
+// Interface and Qualifier show how the bean To is selected. Inherited means the bean To belongs to an ancestor.
+type GraphEdge struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Kind      string `json:"kind"`
+	Interface string `json:"interface,omitempty"`
+	Qualifier string `json:"qualifier,omitempty"`
+	Deferred  bool   `json:"deferred,omitempty"`
+	Optional  bool   `json:"optional,omitempty"`
+	Inherited bool   `json:"inherited,omitempty"`
+}
+
+func (c *Container) Graph() (*Graph, error) {
+	l, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	r := l.register
+	graph := &Graph{
+		Beans:      make([]GraphBean, 0, len(r.sMap)),
+		Interfaces: make([]GraphInterface, 0, len(r.iMap)),
+		Edges:      make([]GraphEdge, 0),
+	}
+	for _, id := range sortedKeys(r.sMap) {
+		meta := r.sMap[id]
+		graph.Beans = append(graph.Beans, GraphBean{
+			ID:        id,
+			Name:      meta.option.name,
+			Scope:     meta.option.scopeString(),
+			BuildType: meta.buildType.String(),
+		})
+		for _, info := range meta.dependency {
+			for _, target := range r.resolvedTargets(info) {
+				edge := GraphEdge{
+					From:      id,
+					To:        target,
+					Kind:      info.kind.String(),
+					Deferred:  info.deferred,
+					Optional:  info.optional,
+					Inherited: r.owner(target) != r,
+				}
+				if info.kind != structKind {
+					edge.Interface = info.name
+					edge.Qualifier = info.qualifier
+				}
+				graph.Edges = append(graph.Edges, edge)
+			}
+		}
+	}
+	for _, id := range sortedKeys(r.iMap) {
+		bindInfo := r.iMap[id]
+		implementations := make(map[string]string, len(bindInfo.nameMapping))
+		for name, each := range bindInfo.nameMapping {
+			implementations[name] = each
+		}
+		graph.Interfaces = append(graph.Interfaces, GraphInterface{
+			ID:              id,
+			Primary:         bindInfo.primary,
+			Implementations: implementations,
+		})
+	}
+	return graph, nil
+}
+
+func (g *Graph) edgeLabel(edge GraphEdge) string {
+	var labels []string
+	if edge.Interface != "" {
+		labels = append(labels, shortName(edge.Interface))
+	}
+	if edge.Kind == "slice" || edge.Kind == "map" {
+		labels = append(labels, edge.Kind)
+	}
+	if edge.Qualifier != "" {
+		labels = append(labels, "qualifier="+edge.Qualifier)
+	}
+	if edge.Optional {
+		labels = append(labels, "optional")
+	}
+	if edge.Inherited {
+		labels = append(labels, "parent")
+	}
+	return strings.Join(labels, " ")
+}
+
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("digraph vial {\n\trankdir=LR;\n")
+	for _, bean := range g.Beans {
+		builder.WriteString(fmt.Sprintf("\t%q [shape=box label=%q];\n", bean.ID,
+			fmt.Sprintf("%v\n%v\n%v, %v", bean.Name, bean.ID, bean.Scope, bean.BuildType)))
+	}
+	for _, each := range g.Interfaces {
+		builder.WriteString(fmt.Sprintf("\t%q [shape=ellipse style=dashed label=%q];\n", each.ID,
+			fmt.Sprintf("<<interface>>\n%v", each.ID)))
+		for _, name := range sortedKeys(each.Implementations) {
+			style := "dashed"
+			if each.Implementations[name] == each.Primary {
+				style = "bold"
+			}
+			builder.WriteString(fmt.Sprintf("\t%q -> %q [style=%v arrowhead=empty label=%q];\n",
+				each.ID, each.Implementations[name], style, name))
+		}
+	}
+	for _, edge := range g.Edges {
+		style := "solid"
+		if edge.Deferred || edge.Optional {
+			style = "dashed"
+		}
+		builder.WriteString(fmt.Sprintf("\t%q -> %q [style=%v label=%q];\n", edge.From, edge.To, style, g.edgeLabel(edge)))
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	nodes := make(map[string]string)
+	nodeID := func(id string) string {
+		if _, ok := nodes[id]; !ok {
+			nodes[id] = fmt.Sprintf("n%d", len(nodes))
+		}
+		return nodes[id]
+	}
+	escape := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace
+
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+	for _, bean := range g.Beans {
+		builder.WriteString(fmt.Sprintf("\t%v[\"%v<br/>%v<br/>%v, %v\"]\n", nodeID(bean.ID),
+			escape(bean.Name), escape(bean.ID), bean.Scope, bean.BuildType))
+	}
+	for _, each := range g.Interfaces {
+		builder.WriteString(fmt.Sprintf("\t%v([\"#lt;#lt;interface#gt;#gt;<br/>%v\"])\n", nodeID(each.ID), escape(each.ID)))
+		for _, name := range sortedKeys(each.Implementations) {
+			arrow := "-.->"
+			if each.Implementations[name] == each.Primary {
+				arrow = "==>"
+			}
+			builder.WriteString(fmt.Sprintf("\t%v %v|\"%v\"| %v\n", nodeID(each.ID), arrow,
+				escape(name), nodeID(each.Implementations[name])))
+		}
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if edge.Deferred || edge.Optional {
+			arrow = "-.->"
+		}
+		if label := g.edgeLabel(edge); label != "" {
+			builder.WriteString(fmt.Sprintf("\t%v %v|\"%v\"| %v\n", nodeID(edge.From), arrow, escape(label), nodeID(edge.To)))
+		} else {
+			builder.WriteString(fmt.Sprintf("\t%v %v %v\n", nodeID(edge.From), arrow, nodeID(edge.To)))
+		}
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func (g *Graph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
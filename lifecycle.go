@@ -0,0 +1,107 @@
+package vial
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Initializer is implemented by beans which need to run some logic after all dependencies are injected
+type Initializer interface {
+	Init() error
+}
+
+// Destroyer is implemented by beans which need to release resources when the container closes.
+// Beans implementing io.Closer are closed as well if they don't provide any other destroy hook
+type Destroyer interface {
+	Destroy() error
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func hookReceiverType(dataType reflect.Type) reflect.Type {
+	if dataType.Kind() == reflect.Pointer || dataType.Kind() == reflect.Interface {
+		return dataType
+	}
+	return reflect.PointerTo(dataType)
+}
+
+func validateHookMethod(dataType reflect.Type, method string) error {
+	receiverType := hookReceiverType(dataType)
+	if receiverType.Kind() == reflect.Interface {
+		return fmt.Errorf("cannot find method %v on interface type %v", method, getQualifiedClassName(dataType))
+	}
+	m, ok := receiverType.MethodByName(method)
+	if !ok {
+		return fmt.Errorf("type %v has no exported method %v", getQualifiedClassName(dataType), method)
+	}
+	// the first input is the receiver
+	if m.Type.NumIn() != 1 {
+		return fmt.Errorf("method %v of %v should not accept any params", method, getQualifiedClassName(dataType))
+	}
+	if m.Type.NumOut() > 1 || (m.Type.NumOut() == 1 && m.Type.Out(0) != errorType) {
+		return fmt.Errorf("method %v of %v can only return nothing or an error", method, getQualifiedClassName(dataType))
+	}
+	return nil
+}
+
+// hookReceiver returns a pointer to the data, so hooks with pointer receiver can modify a struct value
+func hookReceiver(data interface{}) (reflect.Value, bool) {
+	value := reflect.ValueOf(data)
+	if !value.IsValid() {
+		return value, false
+	}
+	if value.Kind() == reflect.Pointer {
+		return value, !value.IsNil()
+	}
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	return ptr, true
+}
+
+func callHookMethod(receiver reflect.Value, method string) error {
+	result := receiver.MethodByName(method).Call(nil)
+	if len(result) == 1 && !result[0].IsNil() {
+		return result[0].Interface().(error)
+	}
+	return nil
+}
+
+func runInitHook(meta *structMetaInfo, data interface{}) (interface{}, error) {
+	receiver, ok := hookReceiver(data)
+	if !ok {
+		return data, nil
+	}
+	var err error
+	if meta.option.initMethod != "" {
+		err = callHookMethod(receiver, meta.option.initMethod)
+	} else if initializer, implemented := receiver.Interface().(Initializer); implemented {
+		err = initializer.Init()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init %v failed: %w", meta.name, err)
+	}
+	if reflect.ValueOf(data).Kind() == reflect.Pointer {
+		return data, nil
+	}
+	return receiver.Elem().Interface(), nil
+}
+
+func runDestroyHook(meta *structMetaInfo, data interface{}) error {
+	receiver, ok := hookReceiver(data)
+	if !ok {
+		return nil
+	}
+	var err error
+	if meta.option.destroyMethod != "" {
+		err = callHookMethod(receiver, meta.option.destroyMethod)
+	} else if destroyer, implemented := receiver.Interface().(Destroyer); implemented {
+		err = destroyer.Destroy()
+	} else if closer, isCloser := receiver.Interface().(io.Closer); isCloser {
+		err = closer.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("destroy %v failed: %w", meta.name, err)
+	}
+	return nil
+}
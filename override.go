@@ -0,0 +1,210 @@
+package vial
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+)
+
+type pendingOverride struct {
+	target reflect.Type
+	name   string
+	meta   *structMetaInfo
+}
+
+// Override replaces the registered struct, or the primary struct of the bound interface, with the constructor or
+// the instance. It's applied in Done if it's called before, otherwise the affected singletons are rebuilt, and the
+// created ones replaced are destroyed. The target can be a NamedStruct to replace the bean with the name
+func (c *Container) Override(target interface{}, replacement interface{}) {
+	if err := c.TryOverride(target, replacement); err != nil {
+		panic(err)
+	}
+}
+
+func (c *Container) TryOverride(target interface{}, replacement interface{}) error {
+	// 1. find the overridden type, the pointer of an interface means the interface
+	targetType, name := reflect.TypeOf(target), ""
+	if named, ok := target.(namedStruct); ok {
+		targetType, name = named.dataType, named.name
+	}
+	if targetType == nil {
+		return &InvalidDefinitionError{"nil", fmt.Errorf("cannot override a nil type")}
+	}
+	if targetType.Kind() == reflect.Pointer && targetType.Elem().Kind() == reflect.Interface {
+		targetType = targetType.Elem()
+	}
+
+	// 2. build the replacement, the constructor is checked in the same way as RegisterConstructor
+	meta, err := newReplacement(replacement)
+	if err != nil {
+		return err
+	}
+	if _, err = c.current(); err != nil {
+		c.overrides = append(c.overrides, pendingOverride{targetType, name, meta})
+		return nil
+	}
+
+	// 3. replace it in a copy of the registrations and check them again, the current ones are kept if it fails
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	old, err := c.current()
+	if err != nil {
+		return err
+	}
+	r := newRegisterCloner().clone(old.register)
+	if err = c.linkParent(r); err != nil {
+		return err
+	}
+	message, err := r.override(targetType, name, meta)
+	if err != nil {
+		return err
+	}
+	if err = r.ScanAndCheck(c.scopes, c.properties); err != nil {
+		return err
+	}
+
+	// 4. publish the new collection, the singletons relying on the replacement are built again when they're required
+	l := newCollection(c, r)
+	replaced := r.dependents(map[string]bool{meta.name: true}, nil)
+	l.keep(old, replaced)
+	c.publish(l)
+	log.Print(message)
+
+	// 5. destroy the replaced singletons, and link the children to the replacement
+	destroyDropped(old, l)
+	c.refreshChildren(replaced)
+	return nil
+}
+
+func newReplacement(replacement interface{}) (*structMetaInfo, error) {
+	temp := newRegister()
+	var err error
+	if replacementType := reflect.TypeOf(replacement); replacementType != nil && replacementType.Kind() == reflect.Func {
+		err = temp.RegisterConstruct(replacement)
+	} else {
+		err = temp.RegisterInstance(replacement)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range temp.sMap {
+		return meta, nil
+	}
+	return nil, nil
+}
+
+// applyOverrides applies the overrides called before Done, and returns the messages logged when Done succeeds
+func (r *register) applyOverrides(overrides []pendingOverride) ([]string, error) {
+	errs := &MultiError{}
+	messages := make([]string, 0, len(overrides))
+	for _, each := range overrides {
+		message, err := r.override(each.target, each.name, each.meta)
+		errs.add(err)
+		messages = append(messages, message)
+	}
+	return messages, errs.errorOrNil()
+}
+
+// override puts the replacement into the register, and returns the message logged when it's applied. The struct
+// or the binding of the parent container is shadowed by the replacement, the parent one is not changed
+func (r *register) override(targetType reflect.Type, name string, meta *structMetaInfo) (string, error) {
+	id := getQualifiedClassName(targetType)
+	if targetType.Kind() == reflect.Interface && name != "" {
+		return "", &InvalidDefinitionError{id, fmt.Errorf("NamedStruct cannot refer to the interface %v", id)}
+	}
+	if targetType.Kind() == reflect.Interface {
+		bindInfo, ok := r.iMap[id]
+		if !ok {
+			inherited := r.parent.findInterface(id)
+			if inherited == nil {
+				return "", &MissingBindingError{Name: id, Interface: true}
+			}
+			bindInfo = &interfaceMetaInfo{id: id, primary: inherited.primary, others: inherited.others}
+			r.iMap[id] = bindInfo
+		}
+		if !meta.originType.Implements(targetType) {
+			return "", &InvalidDefinitionError{id, fmt.Errorf("The replacement %v not implement the interface %v", meta.name, id)}
+		}
+		if old := r.sMap[meta.name]; old != nil {
+			meta.option = inheritOption(old.option, meta)
+		}
+		// the replacement takes the bean name of the replaced primary, so the qualifiers of it still work
+		primaryKey, _ := r.bindingKey(id, bindInfo.primary)
+		if replaced := r.findStruct(primaryKey); replaced != nil {
+			meta.option.name = replaced.option.name
+		}
+		r.sMap[meta.name] = meta
+		primary, others := bindInfo.primary, bindInfo.others
+		bindInfo.primary = meta.name
+		bindInfo.others = make(map[string]bool, len(others))
+		for name := range others {
+			if name != primary {
+				bindInfo.others[name] = true
+			}
+		}
+		bindInfo.others[meta.name] = true
+		return fmt.Sprintf("vial: override the primary struct of %v, %v is replaced by the %v of %v", id, primary, meta.buildType, meta.name), nil
+	}
+
+	key, _, found := r.lookupStruct(id, name)
+	if !found {
+		return "", &MissingBindingError{Name: key}
+	}
+	old := r.findStruct(key)
+	if name == "" && len(old.ambiguous) > 0 {
+		return "", &AmbiguousBeanError{Name: id, Beans: old.ambiguous}
+	}
+	if meta.name != id {
+		return "", &InvalidDefinitionError{id, fmt.Errorf("The replacement %v is not the type %v", meta.name, id)}
+	}
+	meta.option = inheritOption(old.option, meta)
+	meta.name = key
+	r.sMap[key] = meta
+	return fmt.Sprintf("vial: override %v, the %v is replaced by the %v", key, old.buildType, meta.buildType), nil
+}
+
+// inheritOption keeps the bean name and the scope of the overridden struct, so the qualifiers still work,
+// the instance is always a singleton and the hooks are only kept for the constructor
+func inheritOption(old option, meta *structMetaInfo) option {
+	result := old
+	result.conditions, result.isDefault = nil, false
+	if meta.prebuilt() {
+		result.scope, result.scopeName = singleton, ""
+		result.initMethod, result.destroyMethod = "", ""
+	}
+	return result
+}
+
+// dependents returns the structs and all the structs relying on them directly or indirectly, the inherited ones
+// are the structs of the ancestors replaced
+func (r *register) dependents(names map[string]bool, inherited map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(names))
+	for name := range names {
+		result[name] = true
+	}
+	replaced := func(target string) bool {
+		if owner := r.owner(target); owner != nil && owner != r {
+			return inherited[target]
+		}
+		return result[target]
+	}
+	for changed := true; changed; {
+		changed = false
+		for id, meta := range r.sMap {
+			if result[id] {
+				continue
+			}
+		search:
+			for _, info := range meta.dependency {
+				for _, target := range r.resolvedTargets(info) {
+					if replaced(target) {
+						result[id] = true
+						changed = true
+						break search
+					}
+				}
+			}
+		}
+	}
+	return result
+}
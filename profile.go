@@ -0,0 +1,66 @@
+package vial
+
+import (
+	"os"
+	"strings"
+)
+
+// profilesEnv lists the profiles activated by the environment, separated by commas
+const profilesEnv = "VIAL_PROFILES"
+
+// Env is passed to the conditions of WithCondition, with the active profiles and the property sources
+type Env struct {
+	profiles   map[string]bool
+	properties propertySources
+}
+
+// Profiles returns the active profiles
+func (e Env) Profiles() []string {
+	return sortedKeys(e.profiles)
+}
+
+// AcceptsProfiles checks any of the profiles is active, a profile starting with ! matches when it's not active
+func (e Env) AcceptsProfiles(profiles ...string) bool {
+	for _, profile := range profiles {
+		if strings.HasPrefix(profile, "!") {
+			if !e.profiles[profile[1:]] {
+				return true
+			}
+		} else if e.profiles[profile] {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup finds the property from the property sources of the container
+func (e Env) Lookup(key string) (string, bool) {
+	return e.properties.Lookup(key)
+}
+
+func (e Env) matches(conditions []func(env Env) bool) bool {
+	for _, condition := range conditions {
+		if !condition(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// ActivateProfiles activates the profiles for WithProfile, together with the ones in VIAL_PROFILES.
+// It should be called before Done.
+func (c *Container) ActivateProfiles(profiles ...string) {
+	c.profiles = append(c.profiles, profiles...)
+}
+
+func (c *Container) newEnv() Env {
+	profiles := make(map[string]bool)
+	active := append([]string{}, c.profiles...)
+	active = append(active, strings.Split(os.Getenv(profilesEnv), ",")...)
+	for _, each := range active {
+		if each = strings.TrimSpace(each); each != "" {
+			profiles[each] = true
+		}
+	}
+	return Env{profiles: profiles, properties: c.properties}
+}
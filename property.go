@@ -0,0 +1,506 @@
+package vial
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PropertySource provides the properties for the ${key:default} placeholders in value tags
+type PropertySource interface {
+	Name() string
+	Lookup(key string) (string, bool)
+}
+
+// PropertyLister is implemented by the sources which know all their keys, it's required to bind maps in config structs
+type PropertyLister interface {
+	Keys() []string
+}
+
+type propertySources []PropertySource
+
+func (p propertySources) Lookup(key string) (string, bool) {
+	for _, source := range p {
+		if val, ok := source.Lookup(key); ok {
+			return val, true
+		}
+	}
+	return "", false
+}
+
+type mapSource struct {
+	name   string
+	values map[string]string
+	// envStyle means the keys are like environment variables, db.port is looked up as DB_PORT as well
+	envStyle bool
+}
+
+func (m *mapSource) Name() string {
+	return m.name
+}
+
+func (m *mapSource) Keys() []string {
+	return sortedKeys(m.values)
+}
+
+func (m *mapSource) Lookup(key string) (string, bool) {
+	val, ok := m.values[key]
+	if !ok && m.envStyle {
+		val, ok = m.values[envName(key)]
+	}
+	return val, ok
+}
+
+// NewMapSource creates a PropertySource with the fixed properties
+func NewMapSource(name string, values map[string]string) PropertySource {
+	return &mapSource{name: name, values: values}
+}
+
+type envSource struct {
+	prefix string
+}
+
+func (e *envSource) Name() string {
+	return "env"
+}
+
+func (e *envSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(e.prefix + envName(key))
+}
+
+// NewEnvSource creates a PropertySource of the environment variables, the key db.port is looked up as prefix + DB_PORT
+func NewEnvSource(prefix string) PropertySource {
+	return &envSource{prefix: prefix}
+}
+
+type flagSource struct {
+	flags *flag.FlagSet
+}
+
+func (f *flagSource) Name() string {
+	return "flag " + f.flags.Name()
+}
+
+func (f *flagSource) Lookup(key string) (string, bool) {
+	var val string
+	found := false
+	f.flags.Visit(func(each *flag.Flag) {
+		if each.Name == key {
+			val, found = each.Value.String(), true
+		}
+	})
+	return val, found
+}
+
+func (f *flagSource) Keys() []string {
+	var keys []string
+	f.flags.Visit(func(each *flag.Flag) {
+		keys = append(keys, each.Name)
+	})
+	return keys
+}
+
+// NewFlagSource creates a PropertySource of the flags set in the command line, the flag defaults are not used
+// so that the properties with lower precedence still work
+func NewFlagSource(flags *flag.FlagSet) PropertySource {
+	return &flagSource{flags: flags}
+}
+
+// PropertyReloader is implemented by the sources which cache their properties, they are reloaded by Container.Refresh.
+// Reload returns a new source with the current properties, the reloaded source is not changed.
+type PropertyReloader interface {
+	Reload() (PropertySource, error)
+}
+
+type fileSource struct {
+	mapSource
+	load func() (map[string]string, error)
+}
+
+func (f *fileSource) Reload() (PropertySource, error) {
+	values, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{mapSource: mapSource{name: f.name, values: values, envStyle: f.envStyle}, load: f.load}, nil
+}
+
+func newFileSource(path string, envStyle bool, load func() (map[string]string, error)) (PropertySource, error) {
+	source := &fileSource{mapSource: mapSource{name: path, envStyle: envStyle}, load: load}
+	return source.Reload()
+}
+
+// NewFileSource creates a PropertySource from a structured file, e.g. NewFileSource("app.yaml", yaml.Unmarshal).
+// The nested keys are joined by dots, and a list is joined by commas.
+func NewFileSource(path string, unmarshal func(data []byte, v interface{}) error) (PropertySource, error) {
+	return newFileSource(path, false, func() (map[string]string, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var data interface{}
+		if err = unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("parse property file %v failed: %w", path, err)
+		}
+		values := make(map[string]string)
+		flattenProperties("", reflect.ValueOf(data), values)
+		return values, nil
+	})
+}
+
+// NewJSONSource creates a PropertySource from a JSON file
+func NewJSONSource(path string) (PropertySource, error) {
+	return NewFileSource(path, json.Unmarshal)
+}
+
+// NewDotEnvSource creates a PropertySource from a .env file of KEY=VALUE lines, the key db.port is looked up as DB_PORT
+// if it's not found
+func NewDotEnvSource(path string) (PropertySource, error) {
+	return newFileSource(path, true, func() (map[string]string, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]string)
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for number := 1; scanner.Scan(); number++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			line = strings.TrimPrefix(line, "export ")
+			index := strings.Index(line, "=")
+			if index <= 0 {
+				return nil, fmt.Errorf("parse property file %v failed: line %d is not KEY=VALUE", path, number)
+			}
+			key, val := strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+1:])
+			if unquoted, err := strconv.Unquote(val); err == nil {
+				val = unquoted
+			} else if len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'' {
+				val = val[1 : len(val)-1]
+			}
+			values[key] = val
+		}
+		return values, scanner.Err()
+	})
+}
+
+func flattenProperties(prefix string, data reflect.Value, values map[string]string) {
+	for data.Kind() == reflect.Interface || data.Kind() == reflect.Pointer {
+		if data.IsNil() {
+			return
+		}
+		data = data.Elem()
+	}
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch data.Kind() {
+	case reflect.Map:
+		for _, key := range data.MapKeys() {
+			flattenProperties(join(fmt.Sprint(key.Interface())), data.MapIndex(key), values)
+		}
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, data.Len())
+		for i := 0; i < data.Len(); i++ {
+			key := fmt.Sprintf("%v[%d]", prefix, i)
+			flattenProperties(key, data.Index(i), values)
+			if item, ok := values[key]; ok && len(items) == i {
+				items = append(items, item)
+			}
+		}
+		// only the list of scalars can be joined
+		if len(items) == data.Len() {
+			values[prefix] = strings.Join(items, ",")
+		}
+	default:
+		values[prefix] = fmt.Sprint(data.Interface())
+	}
+}
+
+// envName converts the property key to the name of environment variable, e.g. db.port to DB_PORT
+func envName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+func hasPlaceholder(text string) bool {
+	return strings.Contains(text, "${")
+}
+
+// resolvePlaceholders replaces all the ${key:default} in the text, the default can contain placeholders as well
+func resolvePlaceholders(text string, lookup func(key string) (string, bool)) (string, error) {
+	var result strings.Builder
+	for {
+		start := strings.Index(text, "${")
+		if start < 0 {
+			result.WriteString(text)
+			return result.String(), nil
+		}
+		result.WriteString(text[:start])
+		depth, end := 0, -1
+		for i := start; i < len(text) && end < 0; i++ {
+			switch {
+			case strings.HasPrefix(text[i:], "${"):
+				depth++
+				i++
+			case text[i] == '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return "", fmt.Errorf("placeholder %v is not closed", text[start:])
+		}
+		key, defaultValue, hasDefault := strings.Cut(text[start+2:end], ":")
+		if key == "" {
+			return "", fmt.Errorf("placeholder %v has no key", text[start:end+1])
+		}
+		if val, ok := lookup(key); ok {
+			result.WriteString(val)
+		} else if hasDefault {
+			val, err := resolvePlaceholders(defaultValue, lookup)
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(val)
+		} else {
+			return "", &MissingPropertyError{Key: key}
+		}
+		text = text[end+1:]
+	}
+}
+
+// validatePlaceholders checks the syntax of the placeholders without looking up the properties
+func validatePlaceholders(text string) error {
+	_, err := resolvePlaceholders(text, func(string) (string, bool) {
+		return "", true
+	})
+	return err
+}
+
+// resolveValue resolves the placeholders of the value tag, and converts the result into the field type
+func resolveValue(name string, info *dependencyInfo, properties propertySources) (reflect.Value, error) {
+	text, err := resolvePlaceholders(info.property, properties.Lookup)
+	var missing *MissingPropertyError
+	if errors.As(err, &missing) {
+		missing.ID = name
+		return reflect.Value{}, missing
+	}
+	var result reflect.Value
+	if err == nil {
+		result, err = convertValue(info.dataType, text)
+	}
+	if err != nil {
+		return reflect.Value{}, &InvalidDefinitionError{name, fmt.Errorf("Parsing Value Tag Error: %w", err)}
+	}
+	return result, nil
+}
+
+// resolveProperties resolves the value tags with placeholders, and binds the config structs
+func (r *register) resolveProperties(properties propertySources, errs *MultiError) {
+	for _, name := range sortedKeys(r.sMap) {
+		for _, info := range r.sMap[name].dependency {
+			if info.kind != valueKind || info.property == "" {
+				continue
+			}
+			result, err := resolveValue(name, info, properties)
+			if err != nil {
+				errs.add(err)
+				continue
+			}
+			info.setValue(result)
+		}
+		if meta := r.sMap[name]; meta.buildType == buildByConfig {
+			instance, err := properties.bindConfig(meta.originType, meta.defaults, meta.prefix)
+			if err != nil {
+				errs.add(&InvalidDefinitionError{name, fmt.Errorf("Binding Config Error: %w", err)})
+				continue
+			}
+			meta.instance = instance
+		}
+	}
+}
+
+// bindConfig creates a deep copy of the defaults, and fills it with the properties under the prefix
+func (p propertySources) bindConfig(configType reflect.Type, defaults interface{}, prefix string) (interface{}, error) {
+	concreteType, level := getConcreteType(configType)
+	target := reflect.New(concreteType)
+	if value := reflect.ValueOf(defaults); level == 0 {
+		target.Elem().Set(deepCopy(value))
+	} else if !value.IsNil() {
+		target.Elem().Set(deepCopy(value.Elem()))
+	}
+	if _, err := p.bind(target.Elem(), prefix); err != nil {
+		return nil, err
+	}
+	if level == 0 {
+		return target.Elem().Interface(), nil
+	}
+	return target.Interface(), nil
+}
+
+// bind fills the target with the properties under the key, and returns whether any property is found
+func (p propertySources) bind(target reflect.Value, key string) (bool, error) {
+	targetType := target.Type()
+	if isScalarType(targetType) {
+		text, ok := p.Lookup(key)
+		if !ok {
+			return false, nil
+		}
+		value, err := convertValue(targetType, text)
+		if err != nil {
+			return false, fmt.Errorf("property %v: %w", key, err)
+		}
+		target.Set(value)
+		return true, nil
+	}
+	switch targetType.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(targetType.Elem())
+		if !target.IsNil() {
+			elem.Elem().Set(target.Elem())
+		}
+		found, err := p.bind(elem.Elem(), key)
+		if found {
+			target.Set(elem)
+		}
+		return found, err
+	case reflect.Struct:
+		found := false
+		for i := 0; i < targetType.NumField(); i++ {
+			field := targetType.Field(i)
+			name := field.Tag.Get(config)
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fieldFound, err := p.bind(target.Field(i), joinPropertyKey(key, name))
+			if err != nil {
+				return false, err
+			}
+			found = found || fieldFound
+		}
+		return found, nil
+	case reflect.Slice:
+		return p.bindSlice(target, key)
+	case reflect.Map:
+		if targetType.Key().Kind() != reflect.String {
+			return false, fmt.Errorf("property %v: map key of %v should be string", key, targetType)
+		}
+		names := p.childKeys(key)
+		if len(names) == 0 {
+			return false, nil
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMap(targetType))
+		}
+		for _, name := range names {
+			elem := reflect.New(targetType.Elem()).Elem()
+			if existing := target.MapIndex(reflect.ValueOf(name).Convert(targetType.Key())); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if _, err := p.bind(elem, joinPropertyKey(key, name)); err != nil {
+				return false, err
+			}
+			target.SetMapIndex(reflect.ValueOf(name).Convert(targetType.Key()), elem)
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("property %v: data type %v cannot be bound", key, targetType)
+	}
+}
+
+// bindSlice binds the indexed properties key[0], key[1]..., or splits the property by commas
+func (p propertySources) bindSlice(target reflect.Value, key string) (bool, error) {
+	targetType := target.Type()
+	var items []reflect.Value
+	for i := 0; p.hasKey(fmt.Sprintf("%v[%d]", key, i)); i++ {
+		elem := reflect.New(targetType.Elem()).Elem()
+		if _, err := p.bind(elem, fmt.Sprintf("%v[%d]", key, i)); err != nil {
+			return false, err
+		}
+		items = append(items, elem)
+	}
+	if len(items) == 0 && isScalarType(targetType.Elem()) {
+		text, ok := p.Lookup(key)
+		if !ok {
+			return false, nil
+		}
+		for i, each := range splitItems(text) {
+			elem, err := convertValue(targetType.Elem(), each)
+			if err != nil {
+				return false, fmt.Errorf("property %v[%d]: %w", key, i, err)
+			}
+			items = append(items, elem)
+		}
+	}
+	if len(items) == 0 {
+		return false, nil
+	}
+	target.Set(reflect.Append(reflect.MakeSlice(targetType, 0, len(items)), items...))
+	return true, nil
+}
+
+// hasKey checks the key or any key under it exists
+func (p propertySources) hasKey(key string) bool {
+	if _, ok := p.Lookup(key); ok {
+		return true
+	}
+	for _, source := range p {
+		if lister, ok := source.(PropertyLister); ok {
+			for _, each := range lister.Keys() {
+				if strings.HasPrefix(each, key+".") || strings.HasPrefix(each, key+"[") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// childKeys returns the sorted names right under the key, from the sources which implement PropertyLister
+func (p propertySources) childKeys(key string) []string {
+	names := make(map[string]bool)
+	for _, source := range p {
+		lister, ok := source.(PropertyLister)
+		if !ok {
+			continue
+		}
+		for _, each := range lister.Keys() {
+			if key != "" {
+				if !strings.HasPrefix(each, key+".") {
+					continue
+				}
+				each = each[len(key)+1:]
+			}
+			if end := strings.IndexAny(each, ".["); end >= 0 {
+				each = each[:end]
+			}
+			if each != "" {
+				names[each] = true
+			}
+		}
+	}
+	return sortedKeys(names)
+}
+
+func joinPropertyKey(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
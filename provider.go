@@ -0,0 +1,117 @@
+package vial
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// dependencyWrapper is implemented by the generic wrappers which can be injected instead of the target type
+type dependencyWrapper interface {
+	targetType() reflect.Type
+	wrap(resolve func() (interface{}, error)) interface{}
+}
+
+var dependencyWrapperType = reflect.TypeOf((*dependencyWrapper)(nil)).Elem()
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func resolveAs[T any](resolve func() (interface{}, error)) (T, error) {
+	var result T
+	if resolve == nil {
+		return result, fmt.Errorf("%v is not injected by vial", getQualifiedClassName(typeOf[T]()))
+	}
+	value, err := resolve()
+	if err != nil || value == nil {
+		return result, err
+	}
+	return value.(T), nil
+}
+
+// Provider resolves the target from the container every time Get is called.
+// It doesn't block cycle injection, and the prototype beans are created on demand.
+type Provider[T any] struct {
+	resolve func() (interface{}, error)
+}
+
+func (p Provider[T]) Get() (T, error) {
+	return resolveAs[T](p.resolve)
+}
+
+func (p Provider[T]) targetType() reflect.Type {
+	return typeOf[T]()
+}
+
+func (p Provider[T]) wrap(resolve func() (interface{}, error)) interface{} {
+	return Provider[T]{resolve: resolve}
+}
+
+type lazyState[T any] struct {
+	once    sync.Once
+	resolve func() (interface{}, error)
+	value   T
+	err     error
+}
+
+// Lazy resolves the target from the container when Get is called for the first time, and keeps the result.
+// It doesn't block cycle injection.
+type Lazy[T any] struct {
+	state *lazyState[T]
+}
+
+func (l Lazy[T]) Get() (T, error) {
+	if l.state == nil {
+		return resolveAs[T](nil)
+	}
+	l.state.once.Do(func() {
+		l.state.value, l.state.err = resolveAs[T](l.state.resolve)
+	})
+	return l.state.value, l.state.err
+}
+
+func (l Lazy[T]) targetType() reflect.Type {
+	return typeOf[T]()
+}
+
+func (l Lazy[T]) wrap(resolve func() (interface{}, error)) interface{} {
+	return Lazy[T]{state: &lazyState[T]{resolve: resolve}}
+}
+
+// optionalWrapper is implemented by Optional, the target is resolved together with the struct if it's registered
+type optionalWrapper interface {
+	targetType() reflect.Type
+	of(value interface{}) interface{}
+}
+
+var optionalWrapperType = reflect.TypeOf((*optionalWrapper)(nil)).Elem()
+
+// Optional holds a dependency which may not be registered in the container
+type Optional[T any] struct {
+	value   T
+	present bool
+}
+
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.present
+}
+
+func (o Optional[T]) OrElse(other T) T {
+	if o.present {
+		return o.value
+	}
+	return other
+}
+
+func (o Optional[T]) targetType() reflect.Type {
+	return typeOf[T]()
+}
+
+func (o Optional[T]) of(value interface{}) interface{} {
+	result := Optional[T]{present: true}
+	if value != nil {
+		result.value = value.(T)
+	}
+	return result
+}
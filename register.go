@@ -1,14 +1,21 @@
 package vial
 
 import (
-	"container/list"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 type register struct {
 	sMap map[string]*structMetaInfo
 	iMap map[string]*interfaceMetaInfo
+	// the structs sorted after the ones they hold, including the ones held by Provider and Lazy
+	order []string
+	// the registrations with conditions or as defaults, they are resolved in Done
+	deferredS []*structMetaInfo
+	deferredI []*interfaceMetaInfo
+	// the register of the parent container, the missing structs and interfaces are found in it
+	parent *register
 }
 
 func newRegister() *register {
@@ -24,7 +31,18 @@ type structMetaInfo struct {
 	option      option
 	originType  reflect.Type
 	constructor reflect.Value
+	instance    interface{}
 	dependency  []*dependencyInfo
+	// the property prefix of the config struct, and the config given by RegisterConfig
+	prefix   string
+	defaults interface{}
+	// the beans of the type, set on the one keyed by the type if several are registered and none is primary
+	ambiguous []string
+}
+
+// prebuilt checks the bean is created before Done returns, so it's never built or destroyed by the container
+func (s *structMetaInfo) prebuilt() bool {
+	return s.buildType == buildByInstance || s.buildType == buildByConfig
 }
 
 type dependencyInfo struct {
@@ -33,115 +51,181 @@ type dependencyInfo struct {
 	qualifier string
 	reference string
 	value     reflect.Value
+	dataType  reflect.Type
+	// the value tag with placeholders, resolved in Done
+	property string
+	// the cell shared by the Dynamic fields
+	dynamic *dynamicCell
+	// the struct field name
+	field string
+	// the Provider, Lazy or Optional type which wraps the dependency
+	wrapper  reflect.Type
+	deferred bool
+	optional bool
+	// the optional dependency is not registered
+	absent bool
+	// the dependency is resolved by the parent container
+	inherited bool
+}
+
+func newDependencyInfo(dataType reflect.Type) *dependencyInfo {
+	if dataType.Kind() != reflect.Interface && dataType.Implements(dependencyWrapperType) {
+		info := newDependencyInfo(reflect.Zero(dataType).Interface().(dependencyWrapper).targetType())
+		info.wrapper = dataType
+		info.deferred = true
+		return info
+	}
+	if dataType.Kind() != reflect.Interface && dataType.Implements(optionalWrapperType) {
+		info := newDependencyInfo(reflect.Zero(dataType).Interface().(optionalWrapper).targetType())
+		info.wrapper = dataType
+		info.optional = true
+		return info
+	}
+	kind := getKindType(dataType)
+	name := getQualifiedClassName(dataType)
+	if kind == interfaceSliceKind || kind == interfaceMapKind {
+		name = getQualifiedClassName(dataType.Elem())
+	}
+	return &dependencyInfo{
+		name:      name,
+		kind:      kind,
+		reference: name,
+		dataType:  dataType,
+	}
 }
 
 type interfaceMetaInfo struct {
+	id          string
 	primary     string
 	others      map[string]bool
 	nameMapping map[string]string
+	conditions  []func(env Env) bool
+	isDefault   bool
 }
 
-func (r *register) RegisterStruct(structure interface{}, options ...applyOption) {
+// implementations returns all the bound structs, the primary one first and the others ordered by bean name
+func (i *interfaceMetaInfo) implementations() []string {
+	result := []string{i.primary}
+	for _, name := range sortedKeys(i.nameMapping) {
+		if i.nameMapping[name] != i.primary {
+			result = append(result, i.nameMapping[name])
+		}
+	}
+	return result
+}
+
+// dependencyTargets returns the structs which are built for the dependency, it should be called after ScanAndCheck
+func (r *register) dependencyTargets(info *dependencyInfo) []string {
+	if info.absent || info.inherited {
+		return nil
+	}
+	switch info.kind {
+	case valueKind:
+		return nil
+	case interfaceSliceKind, interfaceMapKind:
+		return r.iMap[info.name].implementations()
+	default:
+		return []string{info.reference}
+	}
+}
+
+func (r *register) RegisterStruct(structure interface{}, options ...applyOption) error {
 	// 1. Check the first input is valid
 	inputType := reflect.TypeOf(structure)
 	structureType, _ := getConcreteType(inputType)
 	id := getQualifiedClassName(inputType)
 	if structureType.Kind() != reflect.Struct {
-		panic(fmt.Sprintf("Input elem %v is not a struct related type", id))
-	}
-
-	// 2. Check the elem existence
-	if _, ok := r.sMap[id]; ok {
-		panic(fmt.Sprintf("Struct %v has been registered already, cannot register twice", id))
+		return &InvalidDefinitionError{id, fmt.Errorf("Input elem %v is not a struct related type", id)}
 	}
 
-	// 3. Check and register the dependency
+	// 2. Check and register the dependency
 	dependency := make([]*dependencyInfo, 0)
 	for i := 0; i < structureType.NumField(); i++ {
 		field := structureType.Field(i)
 		if val, ok := field.Tag.Lookup(value); ok {
 			if !field.IsExported() {
-				panic(fmt.Sprintf("Input type %v contains field %v is unexported, cannot set as auto-wired", id, field.Name))
+				return &InvalidDefinitionError{id, fmt.Errorf("Input type %v contains field %v is unexported, cannot set as auto-wired", id, field.Name)}
 			}
-			parseValue, parseErr := validateDefaultValue(field.Type, val)
-			if parseErr != nil {
-				panic("Parsing Value Tag Error: " + parseErr.Error())
+			info := newValueInfo(field)
+			// the placeholders are resolved from the property sources in Done
+			if hasPlaceholder(val) {
+				if err := validatePlaceholders(val); err != nil {
+					return &InvalidDefinitionError{id, fmt.Errorf("Parsing Value Tag Error: %w", err)}
+				}
+				info.property = val
+			} else {
+				parseValue, parseErr := convertValue(info.dataType, val)
+				if parseErr != nil {
+					return &InvalidDefinitionError{id, fmt.Errorf("Parsing Value Tag Error: %w", parseErr)}
+				}
+				info.setValue(parseValue)
 			}
-			name := getQualifiedClassName(field.Type)
-			dependency = append(dependency, &dependencyInfo{
-				name:      name,
-				kind:      valueKind,
-				value:     parseValue,
-				reference: name,
-			})
-		} else if _, ok = field.Tag.Lookup(autoWire); ok {
+			dependency = append(dependency, info)
+		} else if isInjectedField(field) {
 			if !field.IsExported() {
-				panic(fmt.Sprintf("Input type %v contains field %v is unexported, cannot set as auto-wired", id, field.Name))
+				return &InvalidDefinitionError{id, fmt.Errorf("Input type %v contains field %v is unexported, cannot set as auto-wired", id, field.Name)}
+			}
+			info := newDependencyInfo(field.Type)
+			info.qualifier = field.Tag.Get(qualifier)
+			if _, ok = field.Tag.Lookup(optional); ok {
+				info.optional = true
 			}
-			name := getQualifiedClassName(field.Type)
-			dependency = append(dependency, &dependencyInfo{
-				name:      name,
-				kind:      getKindType(field.Type),
-				qualifier: field.Tag.Get(qualifier),
-				reference: name,
-			})
+			dependency = append(dependency, info)
 		}
 	}
 
-	// 4. set the options
+	// 3. set the options
 	defaultOption := newDefaultOption()
 	defaultOption.name = structureType.Name()
 
 	for _, eachOption := range options {
 		eachOption.apply(&defaultOption)
 	}
+	if err := validateHookOption(inputType, defaultOption); err != nil {
+		return &InvalidDefinitionError{id, err}
+	}
+	if len(defaultOption.params) > 0 {
+		return &InvalidDefinitionError{id, fmt.Errorf("Named can only be used by constructors, use the qualifier tag instead")}
+	}
 
-	// 5. register in the map
-	r.sMap[id] = &structMetaInfo{
+	// 4. register in the map, the existence is checked there
+	return r.addStruct(&structMetaInfo{
 		buildType:  buildByInject,
 		name:       id,
 		option:     defaultOption,
 		originType: inputType,
 		dependency: dependency,
-	}
+	})
 }
 
-func (r *register) RegisterConstruct(constructor interface{}, options ...applyOption) {
+func (r *register) RegisterConstruct(constructor interface{}, options ...applyOption) error {
 	// 1. check input type
 	constructorType := reflect.TypeOf(constructor)
 	if constructorType.Kind() != reflect.Func {
-		panic(fmt.Sprintf("input elem %v is not a func", getQualifiedClassName(constructorType)))
+		id := getQualifiedClassName(constructorType)
+		return &InvalidDefinitionError{id, fmt.Errorf("input elem %v is not a func", id)}
 	}
 	if constructorType.NumOut() == 0 || constructorType.NumOut() > 2 {
-		panic(fmt.Sprintf("constructor can only return 1 or 2 data"))
+		return &InvalidDefinitionError{constructorType.String(), fmt.Errorf("constructor can only return 1 or 2 data")}
 	}
 
 	// 2.1 check return type 1
 	inputType := constructorType.Out(0)
 	concreteType, _ := getConcreteType(inputType)
 	id := getQualifiedClassName(inputType)
-	if _, ok := r.sMap[id]; ok {
-		panic(fmt.Sprintf("Struct %v has been registered already, cannot register twice", id))
-	}
 
 	// 2.2 check return type 2
 	if constructorType.NumOut() == 2 {
 		errOut := constructorType.Out(1)
-		if !errOut.Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-			panic("The second out type of the constructor should be error or implement error interface")
+		if !errOut.Implements(errorType) {
+			return &InvalidDefinitionError{id, fmt.Errorf("The second out type of the constructor should be error or implement error interface")}
 		}
 	}
 
 	// 3. Check dependency (input data)
 	dependency := make([]*dependencyInfo, 0)
 	for i := 0; i < constructorType.NumIn(); i++ {
-		inputField := constructorType.In(i)
-		name := getQualifiedClassName(inputField)
-		dependency = append(dependency, &dependencyInfo{
-			name:      name,
-			kind:      getKindType(inputField),
-			reference: name,
-		})
+		dependency = append(dependency, newDependencyInfo(constructorType.In(i)))
 	}
 
 	// 4. apply the option
@@ -150,128 +234,694 @@ func (r *register) RegisterConstruct(constructor interface{}, options ...applyOp
 	for _, eachOption := range options {
 		eachOption.apply(&defaultOption)
 	}
+	if err := validateHookOption(inputType, defaultOption); err != nil {
+		return &InvalidDefinitionError{id, err}
+	}
+	if len(defaultOption.params) > constructorType.NumIn() {
+		return &InvalidDefinitionError{id, fmt.Errorf("constructor has %d params, but %d Named markers are given", constructorType.NumIn(), len(defaultOption.params))}
+	}
+	for i, name := range defaultOption.params {
+		dependency[i].qualifier = name
+	}
 
 	// 5. add to the map
-	r.sMap[id] = &structMetaInfo{
+	return r.addStruct(&structMetaInfo{
 		buildType:   buildByConstructor,
 		name:        id,
 		option:      defaultOption,
 		originType:  inputType,
 		constructor: reflect.ValueOf(constructor),
 		dependency:  dependency,
+	})
+}
+
+func (r *register) RegisterInstance(instance interface{}, options ...applyOption) error {
+	// 1. check the instance
+	if instance == nil {
+		return &InvalidDefinitionError{"nil", fmt.Errorf("cannot register a nil instance")}
 	}
+	inputType := reflect.TypeOf(instance)
+	concreteType, _ := getConcreteType(inputType)
+	id := getQualifiedClassName(inputType)
+
+	// 2. apply the option, the instance is always a singleton and managed by the caller
+	defaultOption := newDefaultOption()
+	defaultOption.name = concreteType.Name()
+	for _, eachOption := range options {
+		eachOption.apply(&defaultOption)
+	}
+	if defaultOption.scope != singleton {
+		return &InvalidDefinitionError{id, fmt.Errorf("instance %v can only be registered as singleton", id)}
+	}
+	if defaultOption.initMethod != "" || defaultOption.destroyMethod != "" {
+		return &InvalidDefinitionError{id, fmt.Errorf("instance %v is built already, cannot use lifecycle hooks", id)}
+	}
+	if len(defaultOption.params) > 0 {
+		return &InvalidDefinitionError{id, fmt.Errorf("Named can only be used by constructors")}
+	}
+
+	// 3. add to the map
+	return r.addStruct(&structMetaInfo{
+		buildType:  buildByInstance,
+		name:       id,
+		option:     defaultOption,
+		originType: inputType,
+		instance:   instance,
+	})
 }
 
-func (r *register) Bind(i interface{}, primaryStruct interface{}, others ...interface{}) {
+func (r *register) RegisterConfig(defaults interface{}, prefix string, options ...applyOption) error {
+	// 1. check the config type
+	inputType := reflect.TypeOf(defaults)
+	if inputType == nil {
+		return &InvalidDefinitionError{"nil", fmt.Errorf("cannot register a nil config")}
+	}
+	concreteType, level := getConcreteType(inputType)
+	id := getQualifiedClassName(inputType)
+	if concreteType.Kind() != reflect.Struct || level > 1 {
+		return &InvalidDefinitionError{id, fmt.Errorf("config %v should be a struct or a pointer to struct", id)}
+	}
+
+	// 2. apply the option, the config is bound in Done and shared as a singleton
+	defaultOption := newDefaultOption()
+	defaultOption.name = concreteType.Name()
+	for _, eachOption := range options {
+		eachOption.apply(&defaultOption)
+	}
+	if defaultOption.scope != singleton {
+		return &InvalidDefinitionError{id, fmt.Errorf("config %v can only be registered as singleton", id)}
+	}
+	if defaultOption.initMethod != "" || defaultOption.destroyMethod != "" {
+		return &InvalidDefinitionError{id, fmt.Errorf("config %v is bound from properties, cannot use lifecycle hooks", id)}
+	}
+	if len(defaultOption.params) > 0 {
+		return &InvalidDefinitionError{id, fmt.Errorf("Named can only be used by constructors")}
+	}
+
+	// 3. add to the map
+	return r.addStruct(&structMetaInfo{
+		buildType:  buildByConfig,
+		name:       id,
+		option:     defaultOption,
+		originType: inputType,
+		instance:   defaults,
+		prefix:     prefix,
+		defaults:   defaults,
+	})
+}
+
+func validateHookOption(dataType reflect.Type, opt option) error {
+	for _, method := range []string{opt.initMethod, opt.destroyMethod} {
+		if method == "" {
+			continue
+		}
+		if err := validateHookMethod(dataType, method); err != nil {
+			return fmt.Errorf("Lifecycle Hook Error: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *register) Bind(i interface{}, primaryStruct interface{}, others ...interface{}) error {
 	interfaceType := reflect.TypeOf(i)
 	if interfaceType.Kind() == reflect.Pointer {
 		interfaceType = interfaceType.Elem()
 	}
 	interfaceID := getQualifiedClassName(interfaceType)
 	if interfaceType.Kind() != reflect.Interface {
-		panic(fmt.Sprintf("Input type %v is not an interface", interfaceID))
+		return &InvalidDefinitionError{interfaceID, fmt.Errorf("Input type %v is not an interface", interfaceID)}
 	}
-	if _, ok := r.iMap[interfaceID]; ok {
-		panic(fmt.Sprintf("Interface %v is already bind", interfaceID))
+	result := &interfaceMetaInfo{id: interfaceID}
+
+	// the options among the structs can only be the conditions
+	bindOption := newDefaultOption()
+	structs := make([]interface{}, 0, len(others))
+	for _, each := range others {
+		if eachOption, ok := each.(applyOption); ok {
+			eachOption.apply(&bindOption)
+			continue
+		}
+		structs = append(structs, each)
+	}
+	result.conditions, result.isDefault = bindOption.conditions, bindOption.isDefault
+	bindOption.conditions, bindOption.isDefault = nil, false
+	if !reflect.DeepEqual(bindOption, newDefaultOption()) {
+		return &InvalidDefinitionError{interfaceID, fmt.Errorf("only WithProfile, WithCondition and WithDefault can be used by Bind")}
 	}
-	result := &interfaceMetaInfo{}
 
-	primaryType := reflect.TypeOf(primaryStruct)
+	primaryType, primaryKey := structTarget(primaryStruct)
 	if !primaryType.Implements(interfaceType) {
-		panic(fmt.Sprintf("The primary struct type %v not implement the interface %v", getQualifiedClassName(primaryType), interfaceID))
+		return &InvalidDefinitionError{interfaceID, fmt.Errorf("The primary struct type %v not implement the interface %v", getQualifiedClassName(primaryType), interfaceID)}
 	}
-	result.primary = getQualifiedClassName(primaryType)
+	result.primary = primaryKey
 
 	otherMap := make(map[string]bool)
-	for _, each := range others {
-		otherType := reflect.TypeOf(each)
-		otherID := getQualifiedClassName(otherType)
+	for _, each := range structs {
+		otherType, otherID := structTarget(each)
 		if !otherType.Implements(interfaceType) {
-			panic(fmt.Sprintf("The struct type %v not implement the interface %v", otherID, interfaceID))
+			return &InvalidDefinitionError{interfaceID, fmt.Errorf("The struct type %v not implement the interface %v", otherID, interfaceID)}
 		}
 		if _, ok := otherMap[otherID]; ok {
-			panic(fmt.Sprintf("Can not bind %v twice on the same interface", otherID))
+			return &InvalidDefinitionError{interfaceID, fmt.Errorf("Can not bind %v twice on the same interface", otherID)}
 		}
 		otherMap[otherID] = true
 	}
 	if _, ok := otherMap[result.primary]; ok {
-		panic(fmt.Sprintf("Can not bind %v twice on the same interface", result.primary))
+		return &InvalidDefinitionError{interfaceID, fmt.Errorf("Can not bind %v twice on the same interface", result.primary)}
 	}
 	otherMap[result.primary] = true
 
 	result.others = otherMap
+	if result.isDefault || len(result.conditions) > 0 {
+		r.deferredI = append(r.deferredI, result)
+		return nil
+	}
+	if _, ok := r.iMap[interfaceID]; ok {
+		return &DuplicateRegistrationError{ID: interfaceID, Interface: true}
+	}
 	r.iMap[interfaceID] = result
+	return nil
 }
 
-func (r *register) ScanAndCheck() {
+// structTarget returns the type and the key of the struct given to Bind, the key of a NamedStruct has the bean
+// name, and it's resolved to the struct registered in Done
+func structTarget(target interface{}) (reflect.Type, string) {
+	if named, ok := target.(namedStruct); ok {
+		return named.dataType, namedKey(getQualifiedClassName(named.dataType), named.name)
+	}
+	dataType := reflect.TypeOf(target)
+	return dataType, getQualifiedClassName(dataType)
+}
+
+func (r *register) addStruct(meta *structMetaInfo) error {
+	if meta.option.isDefault || len(meta.option.conditions) > 0 {
+		r.deferredS = append(r.deferredS, meta)
+		return nil
+	}
+	return r.putStruct(meta)
+}
+
+// putStruct adds the struct by its key, and replaces the name of the struct by the key. The first struct of
+// a type is keyed by the type, the others of the same type are keyed by the type and the bean name
+func (r *register) putStruct(meta *structMetaInfo) error {
+	key := meta.name
+	if first, ok := r.sMap[key]; ok && first.option.name != meta.option.name {
+		key = namedKey(meta.name, meta.option.name)
+	}
+	if _, ok := r.sMap[key]; ok {
+		return &DuplicateRegistrationError{ID: key}
+	}
+	meta.name = key
+	r.sMap[key] = meta
+	return nil
+}
+
+func namedKey(id string, name string) string {
+	return id + "#" + name
+}
+
+// structKey finds the struct of the type with the bean name, the first struct of the type is used if the name is empty
+func (r *register) structKey(id string, name string) (string, bool) {
+	if first, ok := r.sMap[id]; ok && (name == "" || first.option.name == name) {
+		return id, true
+	}
+	if name == "" {
+		return id, false
+	}
+	_, ok := r.sMap[namedKey(id, name)]
+	return namedKey(id, name), ok
+}
+
+// resolveDeferred adds the registrations whose conditions pass and drops the others, then adds the
+// defaults if nothing else is registered with the same id
+func (r *register) resolveDeferred(env Env) error {
+	errs := &MultiError{}
+	for _, isDefault := range []bool{false, true} {
+		for _, meta := range r.deferredS {
+			if meta.option.isDefault != isDefault || !env.matches(meta.option.conditions) {
+				continue
+			}
+			// the default is dropped if any struct of the same type is registered
+			if _, ok := r.sMap[meta.name]; ok && isDefault {
+				continue
+			}
+			errs.add(r.putStruct(meta))
+		}
+		for _, bindInfo := range r.deferredI {
+			if bindInfo.isDefault != isDefault || !env.matches(bindInfo.conditions) {
+				continue
+			}
+			if _, ok := r.iMap[bindInfo.id]; ok {
+				if !isDefault {
+					errs.add(&DuplicateRegistrationError{ID: bindInfo.id, Interface: true})
+				}
+				continue
+			}
+			r.iMap[bindInfo.id] = bindInfo
+		}
+	}
+	r.deferredS, r.deferredI = nil, nil
+	// the overrides are applied to the primary beans, the conflicts of them are reported by ScanAndCheck
+	_ = r.resolvePrimary()
+	return errs.errorOrNil()
+}
+
+// resolvePrimary keys the primary bean of a type by the type, so it's the one injected without a qualifier.
+// If several beans of a type are registered and none is primary, they can only be injected by name
+func (r *register) resolvePrimary() error {
+	errs := &MultiError{}
+	types := make(map[string][]string)
+	for _, key := range sortedKeys(r.sMap) {
+		id, _, _ := strings.Cut(key, "#")
+		types[id] = append(types[id], key)
+	}
+	for _, id := range sortedKeys(types) {
+		first, ok := r.sMap[id]
+		// a child may only override the named beans of its parent
+		if !ok {
+			continue
+		}
+		first.ambiguous = nil
+		keys := types[id]
+		if len(keys) == 1 {
+			continue
+		}
+		var primaries []string
+		for _, key := range keys {
+			if r.sMap[key].option.primary {
+				primaries = append(primaries, key)
+			}
+		}
+		switch {
+		case len(primaries) == 0:
+			first.ambiguous = keys
+		case len(primaries) > 1:
+			first.ambiguous = primaries
+			errs.add(&InvalidDefinitionError{id, fmt.Errorf("%v are all marked as primary", strings.Join(primaries, ", "))})
+		case primaries[0] != id:
+			primary := r.sMap[primaries[0]]
+			delete(r.sMap, primaries[0])
+			first.name, primary.name = namedKey(id, first.option.name), id
+			r.sMap[first.name], r.sMap[id] = first, primary
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// resolveBinding replaces the keys given to Bind by the keys of the structs registered, it reports false if
+// any of them cannot be resolved
+func (r *register) resolveBinding(bindInfo *interfaceMetaInfo, errs *MultiError) bool {
+	count := len(errs.Errors)
+	others := make(map[string]bool, len(bindInfo.others))
+	for _, key := range sortedKeys(bindInfo.others) {
+		resolved, err := r.bindingKey(bindInfo.id, key)
+		if err != nil {
+			errs.add(err)
+			continue
+		}
+		if others[resolved] {
+			errs.add(&InvalidDefinitionError{bindInfo.id, fmt.Errorf("Can not bind %v twice on the same interface", resolved)})
+		}
+		others[resolved] = true
+	}
+	if len(errs.Errors) > count {
+		return false
+	}
+	bindInfo.primary, _ = r.bindingKey(bindInfo.id, bindInfo.primary)
+	bindInfo.others = others
+	return true
+}
+
+// bindingKey resolves the struct bound to the interface, the key of a NamedStruct is resolved by the bean name
+func (r *register) bindingKey(interfaceID string, key string) (string, error) {
+	id, name, _ := strings.Cut(key, "#")
+	result, _, found := r.lookupStruct(id, name)
+	if !found {
+		return key, nil
+	}
+	if meta := r.findStruct(result); name == "" && len(meta.ambiguous) > 0 {
+		return result, &AmbiguousBeanError{Name: id, Beans: meta.ambiguous, Path: []string{interfaceID, id}}
+	}
+	return result, nil
+}
+
+func (r *register) ScanAndCheck(scopes map[string]Scope, properties propertySources) error {
+	errs := &MultiError{}
+
+	// 0. Check the custom scopes are registered
+	for _, name := range sortedKeys(r.sMap) {
+		meta := r.sMap[name]
+		if meta.option.scope != customScope {
+			continue
+		}
+		if _, ok := scopes[meta.option.scopeName]; !ok {
+			errs.add(&InvalidDefinitionError{name, fmt.Errorf("scope %v of %v is not registered in vial", meta.option.scopeName, name)})
+		}
+	}
+
+	errs.add(r.resolvePrimary())
+
 	// 1. Scan and Check interface
-	for _, eachInterface := range r.iMap {
+	for _, interfaceID := range sortedKeys(r.iMap) {
+		eachInterface := r.iMap[interfaceID]
+		if !r.resolveBinding(eachInterface, errs) {
+			continue
+		}
 		eachInterface.nameMapping = make(map[string]string)
-		for eachQualifier := range eachInterface.others {
-			if meta, ok := r.sMap[eachQualifier]; ok {
+		for _, eachQualifier := range sortedKeys(eachInterface.others) {
+			if meta := r.findStruct(eachQualifier); meta != nil {
 				if name, exist := eachInterface.nameMapping[meta.option.name]; exist {
-					panic(fmt.Sprintf("%v and %v share the same name %v for interface bind", eachQualifier, name, meta.option.name))
+					errs.add(&NameConflictError{Name: meta.option.name, Interface: interfaceID, Structs: []string{name, eachQualifier}})
+					continue
 				}
 				eachInterface.nameMapping[meta.option.name] = eachQualifier
 			} else {
-				panic(fmt.Sprintf("%v not found registered in vial", eachQualifier))
+				errs.add(&MissingBindingError{Name: eachQualifier, Path: []string{interfaceID, eachQualifier}})
 			}
 		}
 	}
 
-	// 2. scan struct and find possible cycle injection
-	// 0 - not start, 1 - pending, 2 - done
-	checkMap := make(map[string]int)
-	checkList := list.New()
-	for name := range r.sMap {
-		result := r.cycleInjectionCheck(name, checkMap, checkList)
-		if !result {
-			panic(printCycleInjectionLoop(name, checkList))
+	// 2. scan struct and find missing dependencies and all cycle injections
+	checker := &dependencyChecker{
+		register: r,
+		status:   make(map[string]int),
+		index:    make(map[string]int),
+		low:      make(map[string]int),
+		onStack:  make(map[string]bool),
+		errs:     errs,
+	}
+	r.order = make([]string, 0, len(r.sMap))
+	for _, name := range sortedKeys(r.sMap) {
+		checker.check(name, name)
+	}
+	if len(errs.Errors) == 0 {
+		r.order = r.holdingOrder()
+	}
+
+	// 3. singletons are shared by all requests, they cannot rely on request scoped or custom scoped beans
+	if len(errs.Errors) == 0 {
+		for _, name := range sortedKeys(r.sMap) {
+			if r.sMap[name].option.scope != singleton {
+				continue
+			}
+			if path, scoped := r.findScoped(r.sMap[name], []string{name}, map[*structMetaInfo]bool{}); scoped != nil {
+				errs.add(&ScopeError{Name: name, Dependency: scoped.name, Scope: scoped.option.scopeString(), Path: path})
+			}
+		}
+	}
+
+	// 4. resolve the placeholders in value tags and parse them
+	r.resolveProperties(properties, errs)
+	return errs.errorOrNil()
+}
+
+// holdingOrder sorts the structs after the structs they hold, so Close can destroy a struct before the structs
+// it holds. The deferred dependencies are added only if they don't form a cycle, since the direct ones decide
+// which struct is built first
+func (r *register) holdingOrder() []string {
+	edges := make(map[string][]string)
+	var deferred [][2]string
+	for _, name := range sortedKeys(r.sMap) {
+		for _, info := range r.sMap[name].dependency {
+			for _, target := range r.dependencyTargets(info) {
+				if _, ok := r.sMap[target]; !ok {
+					continue
+				}
+				if info.deferred {
+					deferred = append(deferred, [2]string{name, target})
+				} else {
+					edges[name] = append(edges[name], target)
+				}
+			}
+		}
+	}
+	for _, each := range deferred {
+		if !reaches(edges, each[1], each[0]) {
+			edges[each[0]] = append(edges[each[0]], each[1])
+		}
+	}
+
+	result := make([]string, 0, len(r.sMap))
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
 		}
+		visited[name] = true
+		for _, next := range edges[name] {
+			visit(next)
+		}
+		result = append(result, name)
+	}
+	for _, name := range sortedKeys(r.sMap) {
+		visit(name)
 	}
+	return result
 }
 
-func (r *register) cycleInjectionCheck(name string, checkMap map[string]int, checkList *list.List) bool {
-	if checkMap[name] == 2 {
-		return true
-	} else if checkMap[name] == 1 {
+func reaches(edges map[string][]string, from string, to string) bool {
+	visited := make(map[string]bool)
+	var search func(name string) bool
+	search = func(name string) bool {
+		if name == to {
+			return true
+		}
+		if visited[name] {
+			return false
+		}
+		visited[name] = true
+		for _, next := range edges[name] {
+			if search(next) {
+				return true
+			}
+		}
 		return false
 	}
-	checkMap[name] = 1
-	metaInfo := r.sMap[name]
-	if metaInfo == nil {
-		panic(fmt.Sprintf("Not found %v registered in the Vial", name))
-	}
-	for _, info := range metaInfo.dependency {
-		nextName := info.name
-		checkName := info.name
-		if info.kind == interfaceKind {
-			bindInfo, exist := r.iMap[nextName]
-			if !exist {
-				panic(fmt.Sprintf("not find bind information for interface %v", nextName))
+	return search(from)
+}
+
+// findScoped returns the path to a request scoped or custom scoped bean which is built together with the bean,
+// the prototypes of the ancestors are followed as well, since they're built with the bean too
+func (r *register) findScoped(meta *structMetaInfo, path []string, visited map[*structMetaInfo]bool) ([]string, *structMetaInfo) {
+	// Provider and Lazy allow cycles, so the same struct may be met again
+	if visited[meta] {
+		return nil, nil
+	}
+	visited[meta] = true
+	for _, info := range meta.dependency {
+		for _, target := range r.resolvedTargets(info) {
+			owner := r.owner(target)
+			if owner == nil {
+				continue
 			}
-			if info.qualifier != "" {
-				if mapping, found := bindInfo.nameMapping[info.qualifier]; found {
-					info.reference = mapping
-				} else {
-					panic(fmt.Sprintf("qualifier %v not found for interface type %v bind", info.qualifier, info.name))
+			next := owner.sMap[target]
+			nextPath := append(path[:len(path):len(path)], target)
+			if next.option.scope == requestScope || next.option.scope == customScope {
+				return nextPath, next
+			}
+			if next.option.scope == protoType {
+				if result, scoped := owner.findScoped(next, nextPath, visited); scoped != nil {
+					return result, scoped
 				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// resolvedTargets returns the structs the dependency refers to, including the ones inherited from the ancestors
+func (r *register) resolvedTargets(info *dependencyInfo) []string {
+	if !info.inherited {
+		return r.dependencyTargets(info)
+	}
+	if info.kind == interfaceSliceKind || info.kind == interfaceMapKind {
+		return r.parent.findInterface(info.name).implementations()
+	}
+	return []string{info.reference}
+}
+
+// owner returns the register of the struct, it's the register or one of its ancestors
+func (r *register) owner(name string) *register {
+	for each := r; each != nil; each = each.parent {
+		if _, ok := each.sMap[name]; ok {
+			return each
+		}
+	}
+	return nil
+}
+
+// dependencyChecker walks the structs in depth first order, it finds the strongly connected components in the
+// same walk (Tarjan's algorithm), and reports one cycle for each group of structs relying on each other
+type dependencyChecker struct {
+	register *register
+	// 0 - not start, 1 - pending, 2 - done
+	status map[string]int
+	// the struct names and printable names on the current dependency path
+	nodes []string
+	path  []string
+	// the visiting order, the lowest order reachable and the structs whose component is not finished
+	index   map[string]int
+	low     map[string]int
+	stack   []string
+	onStack map[string]bool
+	// the cycles found but not reported, one of them is reported when its component is finished
+	cycles []*CycleError
+	errs   *MultiError
+}
+
+func (d *dependencyChecker) currentPath(last string) []string {
+	path := make([]string, 0, len(d.path)+1)
+	path = append(path, d.path...)
+	return append(path, last)
+}
+
+func (d *dependencyChecker) check(name string, checkName string) {
+	if d.status[name] != 0 {
+		return
+	}
+	d.status[name] = 1
+	d.index[name], d.low[name] = len(d.index), len(d.index)
+	d.stack = append(d.stack, name)
+	d.onStack[name] = true
+	d.nodes = append(d.nodes, name)
+	d.path = append(d.path, checkName)
+	defer func() {
+		d.nodes = d.nodes[:len(d.nodes)-1]
+		d.path = d.path[:len(d.path)-1]
+	}()
+
+	for _, info := range d.register.sMap[name].dependency {
+		if info.kind == valueKind {
+			continue
+		}
+		deferred := info.deferred
+		info.absent, info.inherited = false, false
+		if info.kind == structKind {
+			key, inherited, found := d.register.lookupStruct(info.name, info.qualifier)
+			if !found && info.optional {
+				info.absent = true
+				continue
+			}
+			info.reference, info.inherited = key, inherited
+			if meta := d.register.findStruct(key); meta != nil && info.qualifier == "" && len(meta.ambiguous) > 0 {
+				d.errs.add(&AmbiguousBeanError{Name: info.name, Beans: meta.ambiguous, Path: d.currentPath(info.name)})
+				continue
+			}
+			if inherited {
+				continue
+			}
+			d.checkDependency(key, key, deferred)
+			continue
+		}
+		bindInfo, exist := d.register.iMap[info.name]
+		if !exist {
+			bindInfo = d.register.parent.findInterface(info.name)
+			exist, info.inherited = bindInfo != nil, bindInfo != nil
+		}
+		if !exist && info.optional {
+			info.absent = true
+			continue
+		}
+		if !exist {
+			d.errs.add(&MissingBindingError{Name: info.name, Interface: true, Path: d.currentPath(info.name)})
+			continue
+		}
+		if info.kind == interfaceSliceKind || info.kind == interfaceMapKind {
+			if info.inherited {
+				continue
+			}
+			for _, each := range bindInfo.implementations() {
+				d.checkDependency(each, fmt.Sprintf("%v(%v)", info.name, each), deferred)
+			}
+			continue
+		}
+		if info.qualifier != "" {
+			if mapping, found := bindInfo.nameMapping[info.qualifier]; found {
+				info.reference = mapping
 			} else {
-				info.reference = bindInfo.primary
+				d.errs.add(&QualifierNotFoundError{Qualifier: info.qualifier, Interface: info.name, Path: d.currentPath(info.name)})
+				continue
 			}
-			checkName = fmt.Sprintf("%v(%v)", info.name, info.reference)
-			nextName = info.reference
-		} else if info.kind == valueKind {
+		} else {
+			info.reference = bindInfo.primary
+		}
+		if info.inherited {
 			continue
 		}
+		d.checkDependency(info.reference, fmt.Sprintf("%v(%v)", info.name, info.reference), deferred)
+	}
+	d.status[name] = 2
+	d.register.order = append(d.register.order, name)
+	if d.low[name] == d.index[name] {
+		d.finishComponent(name)
+	}
+}
 
-		el := checkList.PushBack(checkName)
-		result := r.cycleInjectionCheck(nextName, checkMap, checkList)
-		if !result {
-			return false
+// checkDependency checks the dependency exists, the deferred dependency is resolved after the struct is built,
+// so it won't cause cycle injection
+func (d *dependencyChecker) checkDependency(nextName string, checkName string, deferred bool) {
+	if _, ok := d.register.sMap[nextName]; !ok {
+		// the struct bound in the container may be registered in the parent
+		if d.register.parent.findStruct(nextName) != nil {
+			return
 		}
-		checkList.Remove(el)
+		d.errs.add(&MissingBindingError{Name: nextName, Path: d.currentPath(checkName)})
+		return
+	}
+	if deferred {
+		return
+	}
+	current := d.nodes[len(d.nodes)-1]
+	if d.status[nextName] == 0 {
+		d.check(nextName, checkName)
+		if d.low[nextName] < d.low[current] {
+			d.low[current] = d.low[nextName]
+		}
+		return
+	}
+	if d.onStack[nextName] && d.index[nextName] < d.low[current] {
+		d.low[current] = d.index[nextName]
+	}
+	if d.status[nextName] == 1 {
+		d.addCycle(nextName, checkName)
+	}
+}
+
+func (d *dependencyChecker) addCycle(name string, checkName string) {
+	start := len(d.nodes) - 1
+	for d.nodes[start] != name {
+		start--
+	}
+	path := append([]string{name}, d.path[start+1:]...)
+	d.cycles = append(d.cycles, &CycleError{Path: append(path, checkName), start: name})
+}
+
+// finishComponent pops the component rooted at the struct, the first cycle found in it is reported together
+// with all the structs of the component, since they're all on some cycle
+func (d *dependencyChecker) finishComponent(root string) {
+	members := make(map[string]bool)
+	for {
+		last := d.stack[len(d.stack)-1]
+		d.stack = d.stack[:len(d.stack)-1]
+		d.onStack[last] = false
+		members[last] = true
+		if last == root {
+			break
+		}
+	}
+	var reported *CycleError
+	cycles := d.cycles[:0]
+	for _, each := range d.cycles {
+		if !members[each.start] {
+			cycles = append(cycles, each)
+		} else if reported == nil {
+			reported = each
+		}
+	}
+	d.cycles = cycles
+	if reported != nil {
+		reported.Structs = sortedKeys(members)
+		d.errs.add(reported)
 	}
-	checkMap[name] = 2
-	return true
 }
@@ -0,0 +1,116 @@
+package vial
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Scope caches the beans with a custom lifecycle, e.g. one instance per tenant or per job.
+// Get returns the cached bean of the name, or calls build to create one. The scope owns the beans it caches,
+// the container never destroys them, so the scope should release them when they're dropped.
+type Scope interface {
+	Get(ctx context.Context, name string, build func() (interface{}, error)) (interface{}, error)
+}
+
+type scopeKey struct {
+	container *Container
+}
+
+// scopedName is the bean name in the container owning it, a child may register the same struct as its parent
+type scopedName struct {
+	container *Container
+	name      string
+}
+
+type scopedEntry struct {
+	metaInfo *structMetaInfo
+	assigned bool
+	value    interface{}
+	lock     sync.Mutex
+}
+
+// RequestScope caches the request scoped beans, every bean resolved with the same scope shares one instance.
+// The scope should be closed when the request ends.
+type RequestScope struct {
+	container *Container
+	lock      sync.Mutex
+	closed    bool
+	entries   map[scopedName]*scopedEntry
+	created   []*scopedEntry
+}
+
+// NewScope creates a RequestScope and binds it to the returned context. The request scoped beans of the ancestors
+// resolved by the container are kept in the same scope
+func (c *Container) NewScope(ctx context.Context) (context.Context, *RequestScope) {
+	scope := &RequestScope{
+		container: c,
+		entries:   make(map[scopedName]*scopedEntry),
+	}
+	for each := c; each != nil; each = each.parent {
+		ctx = context.WithValue(ctx, scopeKey{each}, scope)
+	}
+	return ctx, scope
+}
+
+func (c *Container) scopeFromContext(ctx context.Context) *RequestScope {
+	scope, _ := ctx.Value(scopeKey{c}).(*RequestScope)
+	return scope
+}
+
+func (s *RequestScope) getValue(owner *Container, meta *structMetaInfo, build func() (interface{}, error)) (interface{}, error) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil, fmt.Errorf("the request scope of %v has been closed", meta.name)
+	}
+	key := scopedName{owner, meta.name}
+	entry := s.entries[key]
+	if entry == nil {
+		entry = &scopedEntry{metaInfo: meta}
+		s.entries[key] = entry
+	}
+	s.lock.Unlock()
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.assigned {
+		return entry.value, nil
+	}
+	result, err := build()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		// Close happened during the build and missed the bean, so it's destroyed here
+		if err = runDestroyHook(meta, result); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("the request scope of %v has been closed", meta.name)
+	}
+	entry.assigned = true
+	entry.value = result
+	s.created = append(s.created, entry)
+	s.lock.Unlock()
+	return result, nil
+}
+
+// Close releases all the beans created in the scope, the destroy hooks are called in the reverse creation order
+func (s *RequestScope) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	created := s.created
+	s.lock.Unlock()
+
+	errs := &MultiError{}
+	for i := len(created) - 1; i >= 0; i-- {
+		errs.add(runDestroyHook(created[i].metaInfo, created[i].value))
+	}
+	return errs.errorOrNil()
+}
@@ -0,0 +1,185 @@
+package vial
+
+import "sync/atomic"
+
+// Snapshot is the registrations and the created singletons of a container, it can be restored to the same
+// container or another one any times
+type Snapshot struct {
+	initType   int
+	register   *register
+	singletons map[string]capturedSingleton
+	// source is the container captured, the singletons are only restored to it
+	source     *Container
+	scopes     map[string]Scope
+	properties propertySources
+	profiles   []string
+	overrides  []pendingOverride
+	parent     *Container
+}
+
+// Snapshot captures the state of the container, the singletons created before are shared with the restored ones
+func (c *Container) Snapshot() *Snapshot {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	cloner := newRegisterCloner()
+	result := &Snapshot{
+		initType:   c.initType,
+		register:   cloner.clone(c.register),
+		singletons: make(map[string]capturedSingleton),
+		source:     c,
+		scopes:     make(map[string]Scope, len(c.scopes)),
+		properties: append(propertySources{}, c.properties...),
+		profiles:   append([]string{}, c.profiles...),
+		overrides:  cloner.cloneOverrides(c.overrides),
+		parent:     c.parent,
+	}
+	if c.collection != nil {
+		for name, entry := range c.collection.singletonMap {
+			if created := entry.load(); created != nil && !entry.metaInfo.prebuilt() {
+				result.singletons[name] = capturedSingleton{entry, created}
+			}
+		}
+	}
+	for name, scope := range c.scopes {
+		result.scopes[name] = scope
+	}
+	return result
+}
+
+// Restore replaces the state of the container by the snapshot, the singletons created after the snapshot
+// are destroyed. If the snapshot is taken before Done, the container can register more. The captured singletons
+// are only restored to the container captured, and only the ones not destroyed by Close or Override since then,
+// another container builds its own singletons. A closed container can be used again after Restore.
+func (c *Container) Restore(snapshot *Snapshot) {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	former := c.parentContainer()
+	old, current := c.restore(snapshot)
+	if former != snapshot.parent {
+		if former != nil {
+			former.detach(c)
+		}
+		if snapshot.parent != nil {
+			snapshot.parent.attach(c)
+		}
+	}
+	destroyDropped(old, current)
+	if current != nil {
+		c.refreshChildren(current.changed(old))
+	}
+}
+
+// restore replaces the fields by the snapshot, and returns the collection replaced and the new one
+func (c *Container) restore(snapshot *Snapshot) (*collection, *collection) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	old := c.collection
+	cloner := newRegisterCloner()
+	c.initType = snapshot.initType
+	c.parent = snapshot.parent
+	c.register = cloner.clone(snapshot.register)
+	c.overrides = cloner.cloneOverrides(snapshot.overrides)
+	c.properties = append(propertySources{}, snapshot.properties...)
+	c.profiles = append([]string{}, snapshot.profiles...)
+	c.scopes = make(map[string]Scope, len(snapshot.scopes))
+	for name, scope := range snapshot.scopes {
+		c.scopes[name] = scope
+	}
+	atomic.StoreInt32(&c.closed, 0)
+	c.collection = nil
+	if c.initType != 1 {
+		return old, nil
+	}
+	c.collection = newCollection(c, c.register)
+	if snapshot.source != c {
+		return old, c.collection
+	}
+	for name, captured := range snapshot.singletons {
+		// the entry is shared rather than copied, so the singleton is still destroyed once
+		if _, ok := c.collection.singletonMap[name]; ok && captured.entry.load() == captured.created {
+			c.collection.singletonMap[name] = captured.entry
+		}
+	}
+	return old, c.collection
+}
+
+// capturedSingleton is a singleton created when the snapshot is taken, it's restored if the entry still holds it
+type capturedSingleton struct {
+	entry   *singletonEntry
+	created *createdValue
+}
+
+// registerCloner copies the registrations, since they're changed by the checks in Done and by Override.
+// The same struct or interface is copied once, so the deferred registrations are still shared with the maps.
+type registerCloner struct {
+	structs    map[*structMetaInfo]*structMetaInfo
+	interfaces map[*interfaceMetaInfo]*interfaceMetaInfo
+}
+
+func newRegisterCloner() *registerCloner {
+	return &registerCloner{
+		structs:    make(map[*structMetaInfo]*structMetaInfo),
+		interfaces: make(map[*interfaceMetaInfo]*interfaceMetaInfo),
+	}
+}
+
+func (r *registerCloner) clone(origin *register) *register {
+	result := newRegister()
+	for name, meta := range origin.sMap {
+		result.sMap[name] = r.cloneStruct(meta)
+	}
+	for name, bindInfo := range origin.iMap {
+		result.iMap[name] = r.cloneInterface(bindInfo)
+	}
+	for _, meta := range origin.deferredS {
+		result.deferredS = append(result.deferredS, r.cloneStruct(meta))
+	}
+	for _, bindInfo := range origin.deferredI {
+		result.deferredI = append(result.deferredI, r.cloneInterface(bindInfo))
+	}
+	result.order = append(result.order, origin.order...)
+	result.parent = origin.parent
+	return result
+}
+
+func (r *registerCloner) cloneOverrides(overrides []pendingOverride) []pendingOverride {
+	result := make([]pendingOverride, 0, len(overrides))
+	for _, each := range overrides {
+		result = append(result, pendingOverride{each.target, each.name, r.cloneStruct(each.meta)})
+	}
+	return result
+}
+
+// cloneStruct copies the struct and its dependencies, the Dynamic cells are shared with the created singletons
+func (r *registerCloner) cloneStruct(meta *structMetaInfo) *structMetaInfo {
+	if result, ok := r.structs[meta]; ok {
+		return result
+	}
+	result := *meta
+	result.dependency = make([]*dependencyInfo, 0, len(meta.dependency))
+	for _, each := range meta.dependency {
+		info := *each
+		result.dependency = append(result.dependency, &info)
+	}
+	r.structs[meta] = &result
+	return &result
+}
+
+func (r *registerCloner) cloneInterface(bindInfo *interfaceMetaInfo) *interfaceMetaInfo {
+	if result, ok := r.interfaces[bindInfo]; ok {
+		return result
+	}
+	result := *bindInfo
+	result.others = make(map[string]bool, len(bindInfo.others))
+	for name, exist := range bindInfo.others {
+		result.others[name] = exist
+	}
+	result.nameMapping = make(map[string]string, len(bindInfo.nameMapping))
+	for name, target := range bindInfo.nameMapping {
+		result.nameMapping[name] = target
+	}
+	r.interfaces[bindInfo] = &result
+	return &result
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type Database struct {
+	DSN string
+}
+
+type Logger interface {
+	Log(msg string) string
+}
+
+type ConsoleLogger struct{}
+
+func (c *ConsoleLogger) Log(msg string) string { return "console: " + msg }
+
+type TenantLogger struct{}
+
+func (t *TenantLogger) Log(msg string) string { return "tenant: " + msg }
+
+type Repository struct {
+	DB  *Database `auto_wire:""`
+	Log Logger    `auto_wire:""`
+}
+
+func newInfrastructure(t *testing.T) (*vial.Container, *int) {
+	created := 0
+	parent := vial.NewContainer()
+	parent.RegisterConstructor(func() *Database {
+		created++
+		return &Database{DSN: "parent"}
+	})
+	vial.RegisterStructToContainer[*ConsoleLogger](parent)
+	vial.BindToContainer[Logger, *ConsoleLogger](parent)
+	parent.Done()
+	return parent, &created
+}
+
+func TestChildSharesParentSingletons(t *testing.T) {
+	parent, created := newInfrastructure(t)
+	for _, module := range []string{"order", "payment"} {
+		child := parent.NewChild()
+		vial.RegisterStructToContainer[Repository](child, vial.WithProtoType())
+		child.Done()
+		repository, err := vial.GetFromContainer[Repository](child)
+		if err != nil || repository.DB.DSN != "parent" || repository.Log.Log(module) != "console: "+module {
+			t.Fatalf("expect the beans of the parent injected, got %+v, %v", repository, err)
+		}
+	}
+	if *created != 1 {
+		t.Fatalf("expect the singleton of the parent created once, got %v", *created)
+	}
+	child := parent.NewChild()
+	child.Done()
+	if db, err := vial.GetFromContainer[*Database](child); err != nil || db.DSN != "parent" {
+		t.Fatalf("expect the struct of the parent resolved by the child, got %+v, %v", db, err)
+	}
+	if loggers, err := vial.GetFromContainer[[]Logger](child); err != nil || len(loggers) != 1 {
+		t.Fatalf("expect the binding of the parent resolved by the child, got %v, %v", loggers, err)
+	}
+}
+
+func TestChildShadowsParent(t *testing.T) {
+	parent, _ := newInfrastructure(t)
+	child := parent.NewChild()
+	child.RegisterConstructor(func() *Database { return &Database{DSN: "tenant"} })
+	vial.RegisterStructToContainer[*TenantLogger](child)
+	// the struct of the binding can be registered in the parent
+	vial.BindToContainer[Logger, *TenantLogger](child, &ConsoleLogger{})
+	vial.RegisterStructToContainer[Repository](child, vial.WithProtoType())
+	child.Done()
+
+	repository, err := vial.GetFromContainer[Repository](child)
+	if err != nil || repository.DB.DSN != "tenant" || repository.Log.Log("x") != "tenant: x" {
+		t.Fatalf("expect the beans of the child injected, got %+v, %v", repository, err)
+	}
+	loggers, err := vial.GetFromContainer[[]Logger](child)
+	if err != nil || len(loggers) != 2 {
+		t.Fatalf("expect both loggers bound in the child, got %v, %v", loggers, err)
+	}
+	if db, _ := vial.GetFromContainer[*Database](parent); db.DSN != "parent" {
+		t.Fatalf("expect the parent not changed by the child, got %+v", db)
+	}
+}
+
+func TestChildOverride(t *testing.T) {
+	parent, _ := newInfrastructure(t)
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[Repository](child, vial.WithProtoType())
+	vial.OverrideInContainer[Logger](child, &TenantLogger{})
+	child.Done()
+	if repository, _ := vial.GetFromContainer[Repository](child); repository.Log.Log("x") != "tenant: x" {
+		t.Fatalf("expect the binding of the parent overridden in the child")
+	}
+	if loggers, _ := vial.GetFromContainer[[]Logger](parent); loggers[0].Log("x") != "console: x" {
+		t.Fatalf("expect the parent not overridden")
+	}
+}
+
+func TestChildBeforeParentDone(t *testing.T) {
+	parent := vial.NewContainer()
+	child := parent.NewChild()
+	if err := child.DoneE(); err == nil {
+		t.Fatalf("expect the child cannot be initialized before the parent")
+	}
+	parent.Done()
+	if err := child.DoneE(); err != nil {
+		t.Fatalf("expect the child initialized after the parent, got %v", err)
+	}
+}
+
+type GatewayHolder struct {
+	Gateway *ClosableGateway `auto_wire:""`
+}
+
+type ChildCheckout struct {
+	Gateway PaymentGateway `auto_wire:""`
+}
+
+func TestChildRebuiltByParentOverride(t *testing.T) {
+	parent := vial.NewContainer()
+	parent.RegisterConstructor(func() *ClosableGateway { return &ClosableGateway{} })
+	parent.Done()
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[*GatewayHolder](child)
+	child.Done()
+	holder, _ := vial.GetFromContainer[*GatewayHolder](child)
+
+	vial.OverrideInContainer[*ClosableGateway](parent, func() *ClosableGateway { return &ClosableGateway{} })
+	if !holder.Gateway.closed {
+		t.Fatalf("expect the replaced singleton of the parent destroyed")
+	}
+	current, err := vial.GetFromContainer[*GatewayHolder](child)
+	gateway, _ := vial.GetFromContainer[*ClosableGateway](parent)
+	if err != nil || current == holder || current.Gateway != gateway {
+		t.Fatalf("expect the singleton of the child built with the replacement, got %+v, %v", current, err)
+	}
+}
+
+func TestChildSeesParentInterfaceOverride(t *testing.T) {
+	parent := vial.NewContainer()
+	registerPayment(parent)
+	parent.Done()
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[ChildCheckout](child, vial.WithProtoType())
+	child.Done()
+
+	vial.OverrideInContainer[PaymentGateway](parent, func() *FakeGateway { return &FakeGateway{} })
+	if checkout, err := vial.GetFromContainer[ChildCheckout](child); err != nil || checkout.Gateway.Charge(1) != "fake" {
+		t.Fatalf("expect the child wired by the override of the parent, got %+v, %v", checkout, err)
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+var closed []string
+
+type ClosablePool struct{}
+
+func (p *ClosablePool) Close() error {
+	closed = append(closed, "pool")
+	return nil
+}
+
+type BrokenConsumer struct{}
+
+func (b *BrokenConsumer) Stop() error {
+	closed = append(closed, "consumer")
+	return errors.New("consumer stop failed")
+}
+
+type ProtoCloser struct{}
+
+func (p ProtoCloser) Destroy() error {
+	closed = append(closed, "proto")
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	closed = nil
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*ClosablePool](ctr)
+	vial.RegisterStructToContainer[*BrokenConsumer](ctr, vial.WithDestroyMethod("Stop"))
+	vial.RegisterStructToContainer[ProtoCloser](ctr, vial.WithProtoType())
+	ctr.Done()
+
+	_, _ = vial.GetFromContainer[*ClosablePool](ctr)
+	_, _ = vial.GetFromContainer[*BrokenConsumer](ctr)
+	_, _ = vial.GetFromContainer[ProtoCloser](ctr)
+
+	err := ctr.Close(context.Background())
+	if err == nil || len(err.(*vial.MultiError).Errors) != 1 {
+		t.Fatalf("expect the stop error to be returned, got %v", err)
+	}
+	if len(closed) != 2 {
+		t.Fatalf("expect both singletons to be closed, got %v", closed)
+	}
+	if err = ctr.Close(context.Background()); err != nil || len(closed) != 2 {
+		t.Fatalf("expect closed singletons not to be closed again, got %v, %v", closed, err)
+	}
+}
+
+func TestCloseCanceled(t *testing.T) {
+	closed = nil
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*ClosablePool](ctr)
+	ctr.Done()
+	_, _ = vial.GetFromContainer[*ClosablePool](ctr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ctr.Close(ctx); !errors.Is(err, context.Canceled) || len(closed) != 0 {
+		t.Fatalf("expect canceled close to stop, got %v, %v", closed, err)
+	}
+}
+
+func TestGetAfterClose(t *testing.T) {
+	closed = nil
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*ClosablePool](ctr)
+	ctr.Done()
+	_, _ = vial.GetFromContainer[*ClosablePool](ctr)
+	if err := ctr.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vial.GetFromContainer[*ClosablePool](ctr); !errors.Is(err, vial.ErrClosed) {
+		t.Fatalf("expect the closed container to refuse Get, got %v", err)
+	}
+	if err := ctr.Close(context.Background()); err != nil || len(closed) != 1 {
+		t.Fatalf("expect the pool to be closed once, got %v, %v", closed, err)
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type Handler interface {
+	Handle() string
+}
+
+type LoginHandler struct{}
+
+func (LoginHandler) Handle() string { return "login" }
+
+type LogoutHandler struct{}
+
+func (*LogoutHandler) Handle() string { return "logout" }
+
+type SearchHandler struct{}
+
+func (SearchHandler) Handle() string { return "search" }
+
+type Router struct {
+	All    []Handler          `auto_wire:""`
+	ByName map[string]Handler `auto_wire:""`
+}
+
+type Dispatcher struct {
+	handlers []Handler
+}
+
+func NewDispatcher(handlers []Handler) *Dispatcher {
+	return &Dispatcher{handlers: handlers}
+}
+
+func TestInjectAllImplementations(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[LoginHandler](ctr)
+	vial.RegisterStructToContainer[*LogoutHandler](ctr, vial.WithName("Logout"))
+	vial.RegisterStructToContainer[SearchHandler](ctr)
+	vial.RegisterStructToContainer[Router](ctr, vial.WithProtoType())
+	ctr.RegisterConstructor(NewDispatcher, vial.WithProtoType())
+	vial.BindToContainer[Handler, SearchHandler](ctr, LoginHandler{}, new(LogoutHandler))
+	ctr.Done()
+
+	router, err := vial.GetFromContainer[Router](ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(router.All) != 3 || router.All[0].Handle() != "search" || router.All[1].Handle() != "login" {
+		t.Fatalf("expect primary first and others ordered by bean name, got %v", router.All)
+	}
+	if len(router.ByName) != 3 || router.ByName["Logout"].Handle() != "logout" {
+		t.Fatalf("expect handlers keyed by bean name, got %v", router.ByName)
+	}
+	dispatcher, _ := vial.GetFromContainer[*Dispatcher](ctr)
+	if len(dispatcher.handlers) != 3 {
+		t.Fatalf("expect constructor param to receive all handlers, got %v", dispatcher.handlers)
+	}
+	handlers, _ := vial.GetFromContainer[[]Handler](ctr)
+	if len(handlers) != 3 {
+		t.Fatalf("expect get all handlers, got %v", handlers)
+	}
+}
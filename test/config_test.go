@@ -0,0 +1,123 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type TLSConfig struct {
+	Enabled bool   `config:"enabled"`
+	Cert    string `config:"cert"`
+}
+
+type Upstream struct {
+	Host   string `config:"host"`
+	Weight int    `config:"weight"`
+}
+
+type ServerConfig struct {
+	Host      string            `config:"host"`
+	Port      int               `config:"port"`
+	Timeout   time.Duration     `config:"timeout"`
+	TLS       *TLSConfig        `config:"tls"`
+	Origins   []string          `config:"origins"`
+	Upstreams []Upstream        `config:"upstreams"`
+	Limits    map[string]int    `config:"limits"`
+	Labels    map[string]string `config:"labels"`
+	Retries   int
+	Internal  string `config:"-"`
+}
+
+type Gateway struct {
+	Config *ServerConfig `auto_wire:""`
+}
+
+func TestConfigDefaultsUnchanged(t *testing.T) {
+	defaults := &ServerConfig{
+		Limits: map[string]int{"read": 1},
+		TLS:    &TLSConfig{Cert: "default.pem"},
+	}
+	ctr := vial.NewContainer()
+	ctr.AddPropertySource(vial.NewMapSource("test", map[string]string{
+		"server.limits.write": "10",
+		"server.tls.enabled":  "true",
+	}))
+	ctr.RegisterConfig(defaults, "server")
+	ctr.Done()
+
+	config, _ := vial.GetFromContainer[*ServerConfig](ctr)
+	if config.Limits["write"] != 10 || !config.TLS.Enabled || config.TLS.Cert != "default.pem" {
+		t.Fatalf("expect the properties to be bound over the defaults, got %+v", config)
+	}
+	if len(defaults.Limits) != 1 || defaults.TLS.Enabled {
+		t.Fatalf("expect the defaults to be unchanged, got %+v", defaults)
+	}
+}
+
+func TestRegisterConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(file, []byte(`{"server": {
+		"host": "0.0.0.0", "timeout": "1m30s", "tls": {"enabled": true},
+		"upstreams": [{"host": "a", "weight": 2}, {"host": "b", "weight": 1}],
+		"limits": {"read": 100, "write": 10}, "retries": 3, "internal": "x"
+	}}`), 0o644)
+	source, err := vial.NewJSONSource(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VIAL_CONFIG_SERVER_PORT", "9090")
+	t.Setenv("VIAL_CONFIG_SERVER_ORIGINS", "a.com, b.com")
+
+	ctr := vial.NewContainer()
+	ctr.SetPropertySources(vial.NewEnvSource("VIAL_CONFIG_"), source)
+	ctr.RegisterConfig(&ServerConfig{Port: 8080, Labels: map[string]string{"team": "core"}}, "server")
+	vial.RegisterStructToContainer[Gateway](ctr, vial.WithProtoType())
+	ctr.Done()
+
+	gateway, err := vial.GetFromContainer[Gateway](ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &ServerConfig{
+		Host:      "0.0.0.0",
+		Port:      9090,
+		Timeout:   90 * time.Second,
+		TLS:       &TLSConfig{Enabled: true},
+		Origins:   []string{"a.com", "b.com"},
+		Upstreams: []Upstream{{Host: "a", Weight: 2}, {Host: "b", Weight: 1}},
+		Limits:    map[string]int{"read": 100, "write": 10},
+		Labels:    map[string]string{"team": "core"},
+		Retries:   3,
+	}
+	if !reflect.DeepEqual(gateway.Config, expect) {
+		t.Fatalf("expect %+v, got %+v", expect, gateway.Config)
+	}
+	again, _ := vial.GetFromContainer[*ServerConfig](ctr)
+	if again != gateway.Config {
+		t.Fatalf("expect the config shared as a singleton")
+	}
+}
+
+func TestRegisterConfigErrors(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.AddPropertySource(vial.NewMapSource("test", map[string]string{"server.timeout": "soon"}))
+	vial.RegisterConfigToContainer[ServerConfig](ctr, "server")
+	var invalid *vial.InvalidDefinitionError
+	if err := ctr.DoneE(); !errors.As(err, &invalid) {
+		t.Fatalf("expect invalid duration reported by Done, got %v", err)
+	}
+
+	ctr = vial.NewContainer()
+	if err := vial.TryRegisterConfigToContainer[ServerConfig](ctr, "server", vial.WithProtoType()); !errors.As(err, &invalid) {
+		t.Fatalf("expect config registered as singleton only, got %v", err)
+	}
+	if err := vial.TryRegisterConfigToContainer[int](ctr, "server"); !errors.As(err, &invalid) {
+		t.Fatalf("expect config should be a struct, got %v", err)
+	}
+}
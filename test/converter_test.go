@@ -0,0 +1,108 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type Port int
+
+type LogLevel int
+
+type Endpoint struct {
+	Host string
+	Port int
+}
+
+type Converted struct {
+	Timeout  time.Duration     `value:"5s"`
+	Deadline time.Time         `value:"2024-01-02"`
+	Hosts    []string          `value:"a, b,c"`
+	Weights  map[string]int    `value:"read=3,write=1"`
+	Port     Port              `value:"8080"`
+	PortPtr  *Port             `value:"9090"`
+	IP       net.IP            `value:"127.0.0.1"`
+	Level    LogLevel          `value:"debug"`
+	Backends []Endpoint        `value:"a:1,b:2"`
+	Delays   []time.Duration   `value:"${delays:1s,2s}"`
+	Limits   map[Port]LogLevel `value:"80=info"`
+}
+
+func init() {
+	vial.RegisterConverter(func(text string) (LogLevel, error) {
+		switch text {
+		case "debug":
+			return 0, nil
+		case "info":
+			return 1, nil
+		}
+		return 0, fmt.Errorf("unknown level %v", text)
+	})
+	vial.RegisterConverter(func(text string) (Endpoint, error) {
+		host, port, _ := strings.Cut(text, ":")
+		var result Endpoint
+		_, err := fmt.Sscan(port, &result.Port)
+		result.Host = host
+		return result, err
+	})
+}
+
+func TestConverters(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[Converted](ctr, vial.WithProtoType())
+	ctr.Done()
+
+	value, err := vial.GetFromContainer[Converted](ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := Port(9090)
+	expect := Converted{
+		Timeout:  5 * time.Second,
+		Deadline: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Hosts:    []string{"a", "b", "c"},
+		Weights:  map[string]int{"read": 3, "write": 1},
+		Port:     8080,
+		PortPtr:  &port,
+		IP:       net.ParseIP("127.0.0.1"),
+		Level:    0,
+		Backends: []Endpoint{{"a", 1}, {"b", 2}},
+		Delays:   []time.Duration{time.Second, 2 * time.Second},
+		Limits:   map[Port]LogLevel{80: 1},
+	}
+	if !reflect.DeepEqual(value, expect) {
+		t.Fatalf("expect %+v, got %+v", expect, value)
+	}
+
+	// the prototype instances don't share the values of reference types
+	value.Hosts[0], value.Weights["read"], *value.PortPtr = "x", 0, 0
+	if another, _ := vial.GetFromContainer[Converted](ctr); !reflect.DeepEqual(another, expect) {
+		t.Fatalf("expect a new instance not to be changed, got %+v", another)
+	}
+}
+
+func TestConverterErrors(t *testing.T) {
+	type BadLevel struct {
+		Level LogLevel `value:"verbose"`
+	}
+	type BadChan struct {
+		Events chan int `value:"1"`
+	}
+	type BadMap struct {
+		Weights map[string]int `value:"read"`
+	}
+	var invalid *vial.InvalidDefinitionError
+	for _, each := range []interface{}{BadLevel{}, BadChan{}, BadMap{}} {
+		err := vial.NewContainer().TryRegisterStructByInstance(each)
+		if !errors.As(err, &invalid) {
+			t.Fatalf("expect invalid definition for %T, got %v", each, err)
+		}
+	}
+}
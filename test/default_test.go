@@ -0,0 +1,73 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type SpanTracer interface {
+	Name() string
+}
+
+type NoopTracer struct{}
+
+func (n *NoopTracer) Name() string { return "noop" }
+
+type JaegerTracer struct{}
+
+func (j *JaegerTracer) Name() string { return "jaeger" }
+
+type TracedHandler struct {
+	Tracer SpanTracer `auto_wire:""`
+}
+
+// registerTracingDefaults is what a shared library does
+func registerTracingDefaults(ctr *vial.Container) {
+	vial.RegisterStructToContainer[*NoopTracer](ctr, vial.WithDefault())
+	vial.BindToContainer[SpanTracer, *NoopTracer](ctr, vial.WithDefault())
+	vial.RegisterStructToContainer[TracedHandler](ctr, vial.WithProtoType(), vial.WithDefault())
+}
+
+func TestDefaultUsedIfAbsent(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerTracingDefaults(ctr)
+	ctr.Done()
+	handler, err := vial.GetFromContainer[TracedHandler](ctr)
+	if err != nil || handler.Tracer.Name() != "noop" {
+		t.Fatalf("expect the default tracer, got %+v, %v", handler, err)
+	}
+}
+
+func TestDefaultOverridden(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.BindToContainer[SpanTracer, *JaegerTracer](ctr)
+	registerTracingDefaults(ctr)
+	vial.RegisterStructToContainer[*JaegerTracer](ctr)
+	// the same struct registered again by the application replaces the default one
+	vial.RegisterStructToContainer[TracedHandler](ctr, vial.WithProtoType(), vial.WithName("handler"))
+	ctr.Done()
+
+	handler, err := vial.GetFromContainer[TracedHandler](ctr)
+	if err != nil || handler.Tracer.Name() != "jaeger" {
+		t.Fatalf("expect the application tracer, got %+v, %v", handler, err)
+	}
+	graph, _ := ctr.Graph()
+	for _, bean := range graph.Beans {
+		if bean.Name == "TracedHandler" {
+			t.Fatalf("expect the default registration dropped")
+		}
+	}
+
+	var duplicate *vial.DuplicateRegistrationError
+	err = vial.TryRegisterStructToContainer[*JaegerTracer](vial.NewContainer(), vial.WithDefault())
+	if err != nil {
+		t.Fatalf("expect default registration accepted, got %v", err)
+	}
+	ctr = vial.NewContainer()
+	vial.RegisterStructToContainer[*JaegerTracer](ctr)
+	if err = vial.TryRegisterStructToContainer[*JaegerTracer](ctr); !errors.As(err, &duplicate) {
+		t.Fatalf("expect duplicate registration without WithDefault, got %v", err)
+	}
+}
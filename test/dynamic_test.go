@@ -0,0 +1,112 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type RateLimiter struct {
+	Limit   vial.Dynamic[int]           `value:"${limit:10}"`
+	Timeout vial.Dynamic[time.Duration] `value:"${timeout}"`
+	Name    string                      `value:"${name:limiter}"`
+	Fixed   vial.Dynamic[string]        `value:"fixed"`
+	changes []vial.ConfigChange
+}
+
+func (r *RateLimiter) OnConfigChange(changes []vial.ConfigChange) error {
+	r.changes = append(r.changes, changes...)
+	return nil
+}
+
+func TestDynamicRefresh(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(file, []byte(`{"timeout": "1s"}`), 0o644)
+	source, err := vial.NewJSONSource(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr := vial.NewContainer()
+	ctr.AddPropertySource(source)
+	vial.RegisterStructToContainer[*RateLimiter](ctr)
+	ctr.Done()
+
+	limiter, err := vial.GetFromContainer[*RateLimiter](ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter.Limit.Get() != 10 || limiter.Timeout.Get() != time.Second || limiter.Fixed.Get() != "fixed" {
+		t.Fatalf("unexpected initial values %v %v", limiter.Limit.Get(), limiter.Timeout.Get())
+	}
+
+	os.WriteFile(file, []byte(`{"timeout": "2s", "limit": 20, "name": "changed"}`), 0o644)
+	if err = ctr.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if limiter.Limit.Get() != 20 || limiter.Timeout.Get() != 2*time.Second || limiter.Name != "limiter" {
+		t.Fatalf("expect only the dynamic values refreshed, got %v %v %v", limiter.Limit.Get(), limiter.Timeout.Get(), limiter.Name)
+	}
+	if len(limiter.changes) != 2 || limiter.changes[0].Field != "Limit" || limiter.changes[0].Old != 10 || limiter.changes[0].New != 20 {
+		t.Fatalf("unexpected changes %+v", limiter.changes)
+	}
+
+	// an invalid value keeps all the current values
+	os.WriteFile(file, []byte(`{"timeout": "3s", "limit": "many"}`), 0o644)
+	var invalid *vial.InvalidDefinitionError
+	if err = ctr.Refresh(); !errors.As(err, &invalid) {
+		t.Fatalf("expect invalid value reported, got %v", err)
+	}
+	if limiter.Timeout.Get() != 2*time.Second || len(limiter.changes) != 2 {
+		t.Fatalf("expect nothing updated by a failed refresh")
+	}
+
+	var missing *vial.MissingPropertyError
+	os.WriteFile(file, []byte(`{}`), 0o644)
+	if err = ctr.Refresh(); !errors.As(err, &missing) || missing.Key != "timeout" {
+		t.Fatalf("expect missing property reported, got %v", err)
+	}
+}
+
+func TestRefreshAllOrNothing(t *testing.T) {
+	dir := t.TempDir()
+	timeoutFile, limitFile := filepath.Join(dir, "timeout.json"), filepath.Join(dir, "limit.json")
+	os.WriteFile(timeoutFile, []byte(`{"timeout": "1s"}`), 0o644)
+	os.WriteFile(limitFile, []byte(`{"limit": 5}`), 0o644)
+	timeoutSource, _ := vial.NewJSONSource(timeoutFile)
+	limitSource, _ := vial.NewJSONSource(limitFile)
+	ctr := vial.NewContainer()
+	ctr.SetPropertySources(timeoutSource, limitSource)
+	vial.RegisterStructToContainer[*RateLimiter](ctr)
+	ctr.Done()
+	limiter, _ := vial.GetFromContainer[*RateLimiter](ctr)
+
+	// the second source fails, so the first one is not switched either
+	os.WriteFile(timeoutFile, []byte(`{"timeout": "2s"}`), 0o644)
+	os.Remove(limitFile)
+	var multi *vial.MultiError
+	if err := ctr.Refresh(); !errors.Is(err, os.ErrNotExist) || errors.As(err, &multi) {
+		t.Fatalf("expect the reload error returned directly, got %v", err)
+	}
+	if value, _ := timeoutSource.Lookup("timeout"); value != "1s" || limiter.Timeout.Get() != time.Second {
+		t.Fatalf("expect nothing reloaded, got %v", value)
+	}
+
+	os.WriteFile(limitFile, []byte(`{"limit": 6}`), 0o644)
+	if err := ctr.Refresh(); err != nil || limiter.Timeout.Get() != 2*time.Second || limiter.Limit.Get() != 6 {
+		t.Fatalf("expect all the sources reloaded, got %v", err)
+	}
+}
+
+func TestDynamicBeforeDone(t *testing.T) {
+	var zero vial.Dynamic[int]
+	if zero.Get() != 0 {
+		t.Fatalf("expect zero value without injection")
+	}
+	if err := vial.NewContainer().Refresh(); err == nil {
+		t.Fatalf("expect error before Done")
+	}
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"errors"
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type Greeter interface {
+	Greet() string
+}
+
+type English struct{}
+
+func (English) Greet() string { return "hello" }
+
+type CycleA struct {
+	B *CycleB `auto_wire:""`
+}
+
+type CycleB struct {
+	A *CycleA `auto_wire:""`
+}
+
+type NeedGreeter struct {
+	G Greeter `auto_wire:"" qualifier:"French"`
+}
+
+type NeedMissing struct {
+	E *English `auto_wire:""`
+}
+
+func TestDuplicateRegistrationError(t *testing.T) {
+	ctr := vial.NewContainer()
+	if err := vial.TryRegisterStructToContainer[English](ctr); err != nil {
+		t.Fatal(err)
+	}
+	var dup *vial.DuplicateRegistrationError
+	if err := vial.TryRegisterStructToContainer[English](ctr); !errors.As(err, &dup) {
+		t.Fatalf("expect DuplicateRegistrationError, got %v", err)
+	}
+	var invalid *vial.InvalidDefinitionError
+	if err := ctr.TryRegisterConstructor(English{}); !errors.As(err, &invalid) {
+		t.Fatalf("expect InvalidDefinitionError, got %v", err)
+	}
+}
+
+func TestDoneErrors(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*CycleA](ctr)
+	vial.RegisterStructToContainer[*CycleB](ctr)
+	var cycle *vial.CycleError
+	if err := ctr.DoneE(); !errors.As(err, &cycle) || len(cycle.Path) != 3 {
+		t.Fatalf("expect CycleError, got %v", err)
+	}
+
+	ctr = vial.NewContainer()
+	vial.RegisterStructToContainer[NeedMissing](ctr)
+	var missing *vial.MissingBindingError
+	if err := ctr.DoneE(); !errors.As(err, &missing) {
+		t.Fatalf("expect MissingBindingError, got %v", err)
+	}
+
+	ctr = vial.NewContainer()
+	vial.RegisterStructToContainer[English](ctr)
+	vial.RegisterStructToContainer[NeedGreeter](ctr)
+	vial.BindToContainer[Greeter, English](ctr)
+	var qualifier *vial.QualifierNotFoundError
+	if err := ctr.DoneE(); !errors.As(err, &qualifier) || qualifier.Qualifier != "French" {
+		t.Fatalf("expect QualifierNotFoundError, got %v", err)
+	}
+}
+
+func TestPanicWrapper(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.Done()
+	if err := vial.TryRegisterStructToContainer[English](ctr); !errors.Is(err, vial.ErrInitialized) {
+		t.Fatalf("expect ErrInitialized, got %v", err)
+	}
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, vial.ErrInitialized) {
+			t.Fatalf("expect panic with ErrInitialized, got %v", err)
+		}
+	}()
+	ctr.Done()
+}
+
+type CycleC struct {
+	D *CycleD `auto_wire:""`
+}
+
+type CycleD struct {
+	C *CycleC `auto_wire:""`
+}
+
+func TestCollectAllErrors(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*CycleA](ctr)
+	vial.RegisterStructToContainer[*CycleB](ctr)
+	vial.RegisterStructToContainer[*CycleC](ctr)
+	vial.RegisterStructToContainer[*CycleD](ctr)
+	vial.RegisterStructToContainer[NeedMissing](ctr)
+	vial.RegisterStructToContainer[NeedGreeter](ctr)
+
+	err := ctr.DoneE()
+	var multi *vial.MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 4 {
+		t.Fatalf("expect 2 cycles and 2 missing bindings, got %v", err)
+	}
+	var missing *vial.MissingBindingError
+	if !errors.As(err, &missing) || len(missing.Path) != 2 {
+		t.Fatalf("expect missing binding with dependency path, got %v", err)
+	}
+	// errors.As calls the As method before Go 1.20, which doesn't unwrap []error
+	var cycle *vial.CycleError
+	if !multi.As(&cycle) || multi.Is(vial.ErrClosed) {
+		t.Fatalf("expect the cycle found by As, got %v", err)
+	}
+}
+
+type CycleE struct {
+	F *CycleF `auto_wire:""`
+}
+
+type CycleF struct {
+	E *CycleE `auto_wire:""`
+	G *CycleG `auto_wire:""`
+}
+
+type CycleG struct {
+	F *CycleF `auto_wire:""`
+}
+
+func TestCycleReportedPerGroup(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*CycleE](ctr)
+	vial.RegisterStructToContainer[*CycleF](ctr)
+	vial.RegisterStructToContainer[*CycleG](ctr)
+
+	err := ctr.DoneE()
+	var multi *vial.MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 1 {
+		t.Fatalf("expect the structs relying on each other to be reported once, got %v", err)
+	}
+	var cycle *vial.CycleError
+	if !errors.As(err, &cycle) || len(cycle.Structs) != 3 {
+		t.Fatalf("expect all the structs on the cycles, got %v", err)
+	}
+}
+
+type PrimaryConsumer struct {
+	DB *DBClient `auto_wire:""`
+}
+
+func TestDoneGathersAllErrors(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(NewTenantCache, vial.WithScope("job"))
+	ctr.RegisterConstructor(func() *DBClient { return &DBClient{} }, vial.WithName("primary"), vial.WithPrimary())
+	ctr.RegisterConstructor(func() *DBClient { return &DBClient{} }, vial.WithName("backup"), vial.WithPrimary())
+	vial.RegisterStructToContainer[PrimaryConsumer](ctr)
+	ctr.Override(vial.NamedStruct[*DBClient]("search"), &DBClient{})
+
+	err := ctr.DoneE()
+	var multi *vial.MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 4 {
+		t.Fatalf("expect the scope, the primary, the ambiguous and the override errors, got %v", err)
+	}
+	var invalid *vial.InvalidDefinitionError
+	var ambiguous *vial.AmbiguousBeanError
+	var missing *vial.MissingBindingError
+	if !errors.As(err, &invalid) || !errors.As(err, &ambiguous) || !errors.As(err, &missing) {
+		t.Fatalf("expect all the kinds of errors reported, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/GarrickZ2/vial"
+	"strings"
+	"testing"
+)
+
+type Notifier interface {
+	Notify()
+}
+
+type MailNotifier struct{}
+
+func (MailNotifier) Notify() {}
+
+type SmsNotifier struct{}
+
+func (SmsNotifier) Notify() {}
+
+type AlertService struct {
+	Primary Notifier   `auto_wire:""`
+	Sms     Notifier   `auto_wire:"" qualifier:"SmsNotifier"`
+	All     []Notifier `auto_wire:""`
+}
+
+func TestGraph(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[MailNotifier](ctr)
+	vial.RegisterStructToContainer[SmsNotifier](ctr, vial.WithProtoType())
+	vial.RegisterStructToContainer[*AlertService](ctr)
+	vial.BindToContainer[Notifier, MailNotifier](ctr, SmsNotifier{})
+	if _, err := ctr.Graph(); err == nil {
+		t.Fatalf("expect error before Done")
+	}
+	ctr.Done()
+
+	graph, err := ctr.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Beans) != 3 || len(graph.Interfaces) != 1 || len(graph.Edges) != 4 {
+		t.Fatalf("unexpected graph %+v", graph)
+	}
+	if graph.Edges[1].Qualifier != "SmsNotifier" || !strings.HasSuffix(graph.Edges[1].To, "SmsNotifier") {
+		t.Fatalf("expect qualifier edge, got %+v", graph.Edges[1])
+	}
+
+	var dot, mermaid, data bytes.Buffer
+	if err = graph.WriteDOT(&dot); err != nil || !strings.HasPrefix(dot.String(), "digraph vial {") {
+		t.Fatalf("unexpected dot output %v, %v", dot.String(), err)
+	}
+	if err = graph.WriteMermaid(&mermaid); err != nil || !strings.Contains(mermaid.String(), "qualifier=SmsNotifier") {
+		t.Fatalf("unexpected mermaid output %v, %v", mermaid.String(), err)
+	}
+	var decoded vial.Graph
+	if err = graph.WriteJSON(&data); err != nil || json.Unmarshal(data.Bytes(), &decoded) != nil || len(decoded.Edges) != 4 {
+		t.Fatalf("unexpected json output %v, %v", data.String(), err)
+	}
+}
+
+func TestChildGraph(t *testing.T) {
+	parent := vial.NewContainer()
+	vial.RegisterStructToContainer[MailNotifier](parent)
+	vial.RegisterStructToContainer[SmsNotifier](parent, vial.WithProtoType())
+	vial.BindToContainer[Notifier, MailNotifier](parent, SmsNotifier{})
+	parent.Done()
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[*AlertService](child)
+	child.Done()
+
+	graph, err := child.Graph()
+	if err != nil || len(graph.Beans) != 1 || len(graph.Edges) != 4 {
+		t.Fatalf("expect the edges to the beans of the parent, got %+v, %v", graph, err)
+	}
+	for _, edge := range graph.Edges {
+		if !edge.Inherited {
+			t.Fatalf("expect the edge marked as inherited, got %+v", edge)
+		}
+	}
+	var dot bytes.Buffer
+	if err = graph.WriteDOT(&dot); err != nil || !strings.Contains(dot.String(), "parent") {
+		t.Fatalf("unexpected dot output %v, %v", dot.String(), err)
+	}
+}
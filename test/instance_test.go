@@ -0,0 +1,63 @@
+package test
+
+import (
+	"context"
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type AppConfig struct {
+	Port int
+}
+
+type Clock interface {
+	Now() int64
+}
+
+type FixedClock struct {
+	At int64
+}
+
+func (f *FixedClock) Now() int64 { return f.At }
+
+func (f *FixedClock) Close() error {
+	f.At = 0
+	return nil
+}
+
+type Server struct {
+	Config *AppConfig `auto_wire:""`
+	Clock  Clock      `auto_wire:""`
+}
+
+func TestRegisterInstance(t *testing.T) {
+	config := &AppConfig{Port: 8080}
+	clock := &FixedClock{At: 42}
+	ctr := vial.NewContainer()
+	ctr.RegisterValue(config)
+	ctr.RegisterValue(clock)
+	vial.RegisterStructToContainer[Server](ctr, vial.WithProtoType())
+	vial.BindToContainer[Clock, *FixedClock](ctr)
+	ctr.Done()
+
+	server, err := vial.GetFromContainer[Server](ctr)
+	if err != nil || server.Config != config || server.Clock.Now() != 42 {
+		t.Fatalf("expect the registered instances injected, got %+v, %v", server, err)
+	}
+	if err = ctr.Close(context.Background()); err != nil || clock.At != 42 {
+		t.Fatalf("expect registered instance not closed by container")
+	}
+	if err = ctr.TryRegisterValue(&AppConfig{}); err == nil {
+		t.Fatalf("expect error after Done")
+	}
+}
+
+func TestRegisterInstanceInvalid(t *testing.T) {
+	ctr := vial.NewContainer()
+	if err := ctr.TryRegisterValue(nil); err == nil {
+		t.Fatalf("expect error for nil instance")
+	}
+	if err := ctr.TryRegisterValue(&AppConfig{}, vial.WithProtoType()); err == nil {
+		t.Fatalf("expect error for prototype instance")
+	}
+}
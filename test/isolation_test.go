@@ -0,0 +1,87 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type EventLog struct {
+	Events []string
+}
+
+type Conn struct {
+	Log *EventLog `auto_wire:""`
+}
+
+func (c *Conn) Destroy() error {
+	c.Log.Events = append(c.Log.Events, "conn")
+	return nil
+}
+
+type Pool struct {
+	Conn *Conn     `auto_wire:""`
+	Log  *EventLog `auto_wire:""`
+}
+
+func (p *Pool) Destroy() error {
+	p.Log.Events = append(p.Log.Events, "pool")
+	return nil
+}
+
+func newIsolatedContainer(data Data) (*vial.Container, *EventLog) {
+	log := &EventLog{}
+	ctr := vial.NewContainer()
+	ctr.RegisterValue(log)
+	vial.RegisterStructToContainer[*Conn](ctr)
+	vial.RegisterStructToContainer[*Pool](ctr)
+	// the same types are registered in the default container by TestVial
+	ctr.RegisterConstructor(func() Data { return data })
+	ctr.RegisterConstructor(NewStructD)
+	ctr.Done()
+	return ctr, log
+}
+
+func TestSingletonsBuiltByOwner(t *testing.T) {
+	first, _ := newIsolatedContainer(1)
+	second, _ := newIsolatedContainer(2)
+	for expected, ctr := range map[float32]*vial.Container{1: first, 2: second} {
+		data, err := vial.GetFromContainer[StructD](ctr)
+		if err != nil || data.Data2 != expected {
+			t.Fatalf("expect the singleton built by its own container, got %+v, %v", data, err)
+		}
+	}
+	firstPool, err := vial.GetFromContainer[*Pool](first)
+	if err != nil {
+		t.Fatalf("expect the singleton with dependencies built, got %v", err)
+	}
+	secondPool, _ := vial.GetFromContainer[*Pool](second)
+	if firstPool == secondPool || firstPool.Conn == secondPool.Conn || firstPool.Log == secondPool.Log {
+		t.Fatalf("expect the singletons not shared between containers")
+	}
+}
+
+func TestCloseIsolatedContainers(t *testing.T) {
+	first, firstLog := newIsolatedContainer(1)
+	second, secondLog := newIsolatedContainer(2)
+	_, _ = vial.GetFromContainer[*Pool](first)
+	_, _ = vial.GetFromContainer[*Pool](second)
+
+	if err := first.Close(context.Background()); err != nil {
+		t.Fatalf("expect close succeeded, got %v", err)
+	}
+	if len(firstLog.Events) != 2 || firstLog.Events[0] != "pool" || firstLog.Events[1] != "conn" {
+		t.Fatalf("expect the dependent destroyed first, got %v", firstLog.Events)
+	}
+	if len(secondLog.Events) != 0 {
+		t.Fatalf("expect the other container not closed, got %v", secondLog.Events)
+	}
+	if pool, err := vial.GetFromContainer[*Pool](second); err != nil || pool.Conn == nil {
+		t.Fatalf("expect the other container still usable, got %+v, %v", pool, err)
+	}
+	_ = second.Close(context.Background())
+	if len(secondLog.Events) != 2 || secondLog.Events[0] != "pool" {
+		t.Fatalf("expect the other container closed by itself, got %v", secondLog.Events)
+	}
+}
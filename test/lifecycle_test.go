@@ -0,0 +1,65 @@
+package test
+
+import (
+	"errors"
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type InitStruct struct {
+	Name  string `value:"pool"`
+	Ready bool
+}
+
+func (s *InitStruct) Init() error {
+	s.Ready = s.Name == "pool"
+	return nil
+}
+
+type MethodHookStruct struct {
+	Started bool
+}
+
+func (s *MethodHookStruct) Start() {
+	s.Started = true
+}
+
+type FailedInit struct{}
+
+func (f *FailedInit) Init() error {
+	return errors.New("connect refused")
+}
+
+func NewFailedInit() *FailedInit {
+	return &FailedInit{}
+}
+
+func TestInitHook(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[InitStruct](ctr, vial.WithProtoType())
+	vial.RegisterStructToContainer[*MethodHookStruct](ctr, vial.WithProtoType(), vial.WithInitMethod("Start"))
+	ctr.RegisterConstructor(NewFailedInit, vial.WithProtoType())
+	ctr.Done()
+
+	s, err := vial.GetFromContainer[InitStruct](ctr)
+	if err != nil || !s.Ready {
+		t.Fatalf("expect Init to be called on value bean, got %+v, %v", s, err)
+	}
+	m, err := vial.GetFromContainer[*MethodHookStruct](ctr)
+	if err != nil || !m.Started {
+		t.Fatalf("expect Start to be called as init method, got %+v, %v", m, err)
+	}
+	if _, err = vial.GetFromContainer[*FailedInit](ctr); err == nil {
+		t.Fatalf("expect init error to be returned")
+	}
+}
+
+func TestInvalidInitMethod(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expect panic for unknown init method")
+		}
+	}()
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[InitStruct](ctr, vial.WithInitMethod("Missing"))
+}
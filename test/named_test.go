@@ -0,0 +1,176 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type DBClient struct {
+	Role string
+}
+
+type DBReader interface {
+	Read() string
+}
+
+func (d *DBClient) Read() string { return d.Role }
+
+type HTTPClient struct {
+	Timeout int
+}
+
+type ReportRepository struct {
+	Primary *DBClient                `auto_wire:""`
+	Replica *DBClient                `auto_wire:"" qualifier:"replica"`
+	Search  vial.Optional[*DBClient] `optional:"" qualifier:"search"`
+}
+
+type ReplicaReader struct {
+	Replica *DBClient
+	Client  HTTPClient
+}
+
+func NewReplicaReader(replica *DBClient, client HTTPClient) *ReplicaReader {
+	return &ReplicaReader{Replica: replica, Client: client}
+}
+
+func registerDatabases(ctr *vial.Container) {
+	// the primary one is injected without the qualifier, wherever it's registered
+	ctr.RegisterConstructor(func() *DBClient { return &DBClient{Role: "replica"} }, vial.WithName("replica"))
+	ctr.RegisterConstructor(func() *DBClient { return &DBClient{Role: "primary"} }, vial.WithName("primary"), vial.WithPrimary())
+	ctr.RegisterValue(HTTPClient{Timeout: 1}, vial.WithName("fast"))
+	ctr.RegisterValue(HTTPClient{Timeout: 30}, vial.WithName("slow"))
+}
+
+func TestNamedBeans(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerDatabases(ctr)
+	vial.RegisterStructToContainer[ReportRepository](ctr, vial.WithProtoType())
+	ctr.RegisterConstructor(NewReplicaReader, vial.Named("replica"), vial.Named("slow"))
+	ctr.Done()
+
+	repository, err := vial.GetFromContainer[ReportRepository](ctr)
+	if err != nil || repository.Primary.Role != "primary" || repository.Replica.Role != "replica" {
+		t.Fatalf("expect the beans injected by the qualifiers, got %+v, %v", repository, err)
+	}
+	if _, ok := repository.Search.Get(); ok {
+		t.Fatalf("expect the missing named bean absent")
+	}
+	reader, err := vial.GetFromContainer[*ReplicaReader](ctr)
+	if err != nil || reader.Replica != repository.Replica || reader.Client.Timeout != 30 {
+		t.Fatalf("expect the constructor params qualified by Named, got %+v, %v", reader, err)
+	}
+	replica, err := vial.GetNamedFromContainer[*DBClient](ctr, "replica")
+	if err != nil || replica != repository.Replica {
+		t.Fatalf("expect the named singleton, got %+v, %v", replica, err)
+	}
+	if primary, err := vial.GetNamedFromContainer[*DBClient](ctr, "primary"); err != nil || primary != repository.Primary {
+		t.Fatalf("expect the primary bean got by its name, got %+v, %v", primary, err)
+	}
+}
+
+func TestGetNamedInterfaceAndParent(t *testing.T) {
+	parent := vial.NewContainer()
+	registerDatabases(parent)
+	registerPayment(parent)
+	parent.Done()
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[ReportRepository](child, vial.WithProtoType())
+	child.Done()
+
+	if repository, err := vial.GetFromContainer[ReportRepository](child); err != nil || repository.Replica.Role != "replica" {
+		t.Fatalf("expect the named beans of the parent injected, got %+v, %v", repository, err)
+	}
+	if client, err := vial.GetNamedFromContainer[HTTPClient](child, "fast"); err != nil || client.Timeout != 1 {
+		t.Fatalf("expect the named bean of the parent, got %+v, %v", client, err)
+	}
+	gateway, err := vial.GetNamedFromContainer[PaymentGateway](child, "StripeGateway")
+	if err != nil || gateway.Charge(1) != "stripe" {
+		t.Fatalf("expect the struct bound to the interface got by its name, got %v, %v", gateway, err)
+	}
+}
+
+func TestNamedBeansError(t *testing.T) {
+	var duplicate *vial.DuplicateRegistrationError
+	ctr := vial.NewContainer()
+	registerDatabases(ctr)
+	err := ctr.TryRegisterConstructor(func() *DBClient { return &DBClient{} }, vial.WithName("replica"))
+	if !errors.As(err, &duplicate) {
+		t.Fatalf("expect the same name registered twice failed, got %v", err)
+	}
+
+	var invalid *vial.InvalidDefinitionError
+	if err = vial.TryRegisterStructToContainer[ReportRepository](ctr, vial.Named("replica")); !errors.As(err, &invalid) {
+		t.Fatalf("expect Named rejected by struct registration, got %v", err)
+	}
+	if err = ctr.TryRegisterConstructor(NewReplicaReader, vial.Named("a"), vial.Named("b"), vial.Named("c")); !errors.As(err, &invalid) {
+		t.Fatalf("expect too many Named markers rejected, got %v", err)
+	}
+
+	var missing *vial.MissingBindingError
+	ctr.RegisterConstructor(NewReplicaReader, vial.Named("backup"))
+	if err = ctr.DoneE(); !errors.As(err, &missing) {
+		t.Fatalf("expect the missing named bean found, got %v", err)
+	}
+}
+
+type HTTPGateway struct {
+	Client HTTPClient `auto_wire:""`
+}
+
+func TestAmbiguousBeans(t *testing.T) {
+	var ambiguous *vial.AmbiguousBeanError
+	ctr := vial.NewContainer()
+	registerDatabases(ctr)
+	ctr.Done()
+	if _, err := vial.GetFromContainer[HTTPClient](ctr); !errors.As(err, &ambiguous) || len(ambiguous.Beans) != 2 {
+		t.Fatalf("expect the unqualified bean ambiguous, got %v", err)
+	}
+	if client, err := vial.GetNamedFromContainer[HTTPClient](ctr, "slow"); err != nil || client.Timeout != 30 {
+		t.Fatalf("expect the named bean got by its name, got %+v, %v", client, err)
+	}
+
+	ctr = vial.NewContainer()
+	registerDatabases(ctr)
+	vial.RegisterStructToContainer[HTTPGateway](ctr)
+	if err := ctr.DoneE(); !errors.As(err, &ambiguous) {
+		t.Fatalf("expect the unqualified injection ambiguous, got %v", err)
+	}
+
+	var invalid *vial.InvalidDefinitionError
+	ctr = vial.NewContainer()
+	registerDatabases(ctr)
+	ctr.RegisterConstructor(func() *DBClient { return &DBClient{} }, vial.WithName("backup"), vial.WithPrimary())
+	if err := ctr.DoneE(); !errors.As(err, &invalid) {
+		t.Fatalf("expect two primary beans rejected, got %v", err)
+	}
+}
+
+func TestNamedStructBindAndOverride(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerDatabases(ctr)
+	ctr.Bind(new(DBReader), vial.NamedStruct[*DBClient]("replica"), vial.NamedStruct[*DBClient]("primary"))
+	ctr.Done()
+	if readers, err := vial.GetFromContainer[[]DBReader](ctr); err != nil || readers[0].Read() != "replica" {
+		t.Fatalf("expect the named bean bound to the interface, got %v, %v", readers, err)
+	}
+	if readers, err := vial.GetFromContainer[map[string]DBReader](ctr); err != nil || readers["primary"].Read() != "primary" {
+		t.Fatalf("expect the named beans bound by their names, got %v, %v", readers, err)
+	}
+
+	vial.OverrideInContainer[*DBClient](ctr, &DBClient{Role: "mock primary"})
+	ctr.Override(vial.NamedStruct[*DBClient]("replica"), &DBClient{Role: "mock replica"})
+	if readers, err := vial.GetFromContainer[[]DBReader](ctr); err != nil || readers[0].Read() != "mock replica" {
+		t.Fatalf("expect the named bean overridden, got %v, %v", readers, err)
+	}
+	if primary, err := vial.GetFromContainer[*DBClient](ctr); err != nil || primary.Role != "mock primary" {
+		t.Fatalf("expect the primary bean overridden, got %+v, %v", primary, err)
+	}
+
+	var ambiguous *vial.AmbiguousBeanError
+	if err := vial.TryOverrideInContainer[HTTPClient](ctr, HTTPClient{}); !errors.As(err, &ambiguous) {
+		t.Fatalf("expect overriding the ambiguous type failed, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type MetricsSink interface {
+	Emit(name string)
+}
+
+type Tracer struct{}
+
+type LibraryClient struct {
+	Metrics MetricsSink   `optional:""`
+	Tracer  *Tracer       `auto_wire:"" optional:""`
+	Sinks   []MetricsSink `optional:""`
+}
+
+type LibraryServer struct {
+	tracer vial.Optional[*Tracer]
+}
+
+func NewLibraryServer(tracer vial.Optional[*Tracer]) *LibraryServer {
+	return &LibraryServer{tracer: tracer}
+}
+
+func TestOptionalAbsent(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[LibraryClient](ctr, vial.WithProtoType())
+	ctr.RegisterConstructor(NewLibraryServer, vial.WithProtoType())
+	if err := ctr.DoneE(); err != nil {
+		t.Fatal(err)
+	}
+	client, err := vial.GetFromContainer[LibraryClient](ctr)
+	if err != nil || client.Metrics != nil || client.Tracer != nil || client.Sinks != nil {
+		t.Fatalf("expect zero values for absent dependencies, got %+v, %v", client, err)
+	}
+	server, _ := vial.GetFromContainer[*LibraryServer](ctr)
+	if _, ok := server.tracer.Get(); ok {
+		t.Fatalf("expect empty optional")
+	}
+}
+
+func TestOptionalPresent(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*Tracer](ctr)
+	ctr.RegisterConstructor(NewLibraryServer, vial.WithProtoType())
+	ctr.Done()
+	server, _ := vial.GetFromContainer[*LibraryServer](ctr)
+	if tracer, ok := server.tracer.Get(); !ok || tracer == nil {
+		t.Fatalf("expect tracer injected")
+	}
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type PaymentGateway interface {
+	Charge(amount int) string
+}
+
+type StripeGateway struct {
+	Region string
+}
+
+func (s *StripeGateway) Charge(amount int) string { return "stripe" }
+
+type FakeGateway struct {
+	Charged []int
+}
+
+func (f *FakeGateway) Charge(amount int) string {
+	f.Charged = append(f.Charged, amount)
+	return "fake"
+}
+
+type CheckoutService struct {
+	Gateway PaymentGateway `auto_wire:""`
+}
+
+func registerPayment(ctr *vial.Container) {
+	ctr.RegisterConstructor(func() *StripeGateway { return &StripeGateway{Region: "us"} })
+	vial.BindToContainer[PaymentGateway, *StripeGateway](ctr)
+	vial.RegisterStructToContainer[CheckoutService](ctr, vial.WithProtoType())
+}
+
+func TestOverrideBeforeDone(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	fake := &FakeGateway{}
+	vial.OverrideInContainer[PaymentGateway](ctr, fake)
+	ctr.Done()
+
+	checkout, err := vial.GetFromContainer[CheckoutService](ctr)
+	if err != nil || checkout.Gateway.Charge(10) != "fake" || len(fake.Charged) != 1 {
+		t.Fatalf("expect the fake gateway injected, got %+v, %v", checkout, err)
+	}
+	gateways, err := vial.GetFromContainer[[]PaymentGateway](ctr)
+	if err != nil || len(gateways) != 1 {
+		t.Fatalf("expect the replaced primary removed from the interface, got %v, %v", gateways, err)
+	}
+}
+
+func TestOverrideAfterDone(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	ctr.Done()
+	if gateway, _ := vial.GetFromContainer[*StripeGateway](ctr); gateway.Region != "us" {
+		t.Fatalf("expect the registered gateway, got %+v", gateway)
+	}
+
+	vial.OverrideInContainer[*StripeGateway](ctr, func() *StripeGateway { return &StripeGateway{Region: "eu"} })
+	gateway, err := vial.GetFromContainer[*StripeGateway](ctr)
+	if err != nil || gateway.Region != "eu" {
+		t.Fatalf("expect the singleton rebuilt by the replacement, got %+v, %v", gateway, err)
+	}
+	checkout, _ := vial.GetFromContainer[CheckoutService](ctr)
+	if checkout.Gateway != PaymentGateway(gateway) {
+		t.Fatalf("expect the new singleton injected")
+	}
+}
+
+func TestOverrideError(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	var missing *vial.MissingBindingError
+	if err := vial.TryOverrideInContainer[*FakeGateway](ctr, &FakeGateway{}); err != nil {
+		t.Fatalf("expect the override delayed to Done, got %v", err)
+	}
+	if err := ctr.DoneE(); !errors.As(err, &missing) {
+		t.Fatalf("expect overriding an unregistered struct failed, got %v", err)
+	}
+
+	ctr = vial.NewContainer()
+	registerPayment(ctr)
+	ctr.Done()
+	err := vial.TryOverrideInContainer[*StripeGateway](ctr, func(_ *FakeGateway) *StripeGateway { return &StripeGateway{} })
+	if !errors.As(err, &missing) {
+		t.Fatalf("expect the missing dependency of the replacement found, got %v", err)
+	}
+	if gateway, err := vial.GetFromContainer[*StripeGateway](ctr); err != nil || gateway.Region != "us" {
+		t.Fatalf("expect the failed override rolled back, got %+v, %v", gateway, err)
+	}
+}
+
+func TestOverrideDestroyReplaced(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(func() *ClosableGateway { return &ClosableGateway{} })
+	ctr.Done()
+	gateway, _ := vial.GetFromContainer[*ClosableGateway](ctr)
+
+	vial.OverrideInContainer[*ClosableGateway](ctr, func() *ClosableGateway { return &ClosableGateway{} })
+	if !gateway.closed {
+		t.Fatalf("expect the replaced singleton destroyed")
+	}
+	if current, _ := vial.GetFromContainer[*ClosableGateway](ctr); current == gateway || current.closed {
+		t.Fatalf("expect a new singleton built by the replacement")
+	}
+}
+
+func TestOverrideConcurrentGet(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	ctr.Done()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if checkout, err := vial.GetFromContainer[CheckoutService](ctr); err != nil || checkout.Gateway == nil {
+					t.Errorf("expect the checkout built during override, got %+v, %v", checkout, err)
+					return
+				}
+			}
+		}()
+	}
+	for _, region := range []string{"eu", "ap", "us"} {
+		region := region
+		vial.OverrideInContainer[*StripeGateway](ctr, func() *StripeGateway { return &StripeGateway{Region: region} })
+	}
+	close(stop)
+	wg.Wait()
+	if gateway, _ := vial.GetFromContainer[*StripeGateway](ctr); gateway.Region != "us" {
+		t.Fatalf("expect the last override kept, got %+v", gateway)
+	}
+}
+
+type QualifiedCheckout struct {
+	Gateway PaymentGateway `auto_wire:"" qualifier:"StripeGateway"`
+}
+
+func TestOverrideKeepsQualifier(t *testing.T) {
+	register := func(ctr *vial.Container) {
+		registerPayment(ctr)
+		vial.RegisterStructToContainer[QualifiedCheckout](ctr, vial.WithProtoType())
+	}
+	before := vial.NewContainer()
+	register(before)
+	vial.OverrideInContainer[PaymentGateway](before, &FakeGateway{})
+	if err := before.DoneE(); err != nil {
+		t.Fatalf("expect the qualifier of the replaced primary kept before Done, got %v", err)
+	}
+	if checkout, err := vial.GetFromContainer[QualifiedCheckout](before); err != nil || checkout.Gateway.Charge(1) != "fake" {
+		t.Fatalf("expect the replacement injected by the qualifier, got %+v, %v", checkout, err)
+	}
+
+	after := vial.NewContainer()
+	register(after)
+	after.Done()
+	if err := vial.TryOverrideInContainer[PaymentGateway](after, &FakeGateway{}); err != nil {
+		t.Fatalf("expect the qualifier of the replaced primary kept after Done, got %v", err)
+	}
+	if checkout, err := vial.GetFromContainer[QualifiedCheckout](after); err != nil || checkout.Gateway.Charge(1) != "fake" {
+		t.Fatalf("expect the replacement injected by the qualifier, got %+v, %v", checkout, err)
+	}
+}
+
+func TestFailedOverrideNotLogged(t *testing.T) {
+	var output bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&output)
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	ctr.Done()
+	if err := vial.TryOverrideInContainer[*StripeGateway](ctr, func(_ *FakeGateway) *StripeGateway { return nil }); err == nil {
+		t.Fatalf("expect the override failed")
+	}
+	if strings.Contains(output.String(), "override") {
+		t.Fatalf("expect the failed override not logged, got %v", output.String())
+	}
+	vial.OverrideInContainer[*StripeGateway](ctr, &StripeGateway{})
+	if !strings.Contains(output.String(), "override") {
+		t.Fatalf("expect the applied override logged")
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type OrderStore interface {
+	Kind() string
+}
+
+type MemoryOrderStore struct{}
+
+func (m *MemoryOrderStore) Kind() string { return "memory" }
+
+type PostgresOrderStore struct{}
+
+func (p *PostgresOrderStore) Kind() string { return "postgres" }
+
+type AuditLog struct{}
+
+type OrderService struct {
+	Store OrderStore `auto_wire:""`
+	Audit *AuditLog  `optional:""`
+}
+
+func newProfileContainer(profiles ...string) *vial.Container {
+	ctr := vial.NewContainer()
+	ctr.ActivateProfiles(profiles...)
+	ctr.AddPropertySource(vial.NewMapSource("test", map[string]string{"audit.enabled": "true"}))
+	vial.RegisterStructToContainer[*MemoryOrderStore](ctr, vial.WithProfile("dev", "test"))
+	vial.RegisterStructToContainer[*PostgresOrderStore](ctr, vial.WithProfile("!test"))
+	vial.BindToContainer[OrderStore, *MemoryOrderStore](ctr, vial.WithProfile("dev", "test"))
+	vial.BindToContainer[OrderStore, *PostgresOrderStore](ctr, vial.WithProfile("prod"))
+	vial.RegisterStructToContainer[*AuditLog](ctr, vial.WithProfile("prod"), vial.WithCondition(func(env vial.Env) bool {
+		enabled, _ := env.Lookup("audit.enabled")
+		return enabled == "true"
+	}))
+	vial.RegisterStructToContainer[OrderService](ctr, vial.WithProtoType())
+	return ctr
+}
+
+func TestProfiles(t *testing.T) {
+	ctr := newProfileContainer("test")
+	ctr.Done()
+	service, err := vial.GetFromContainer[OrderService](ctr)
+	if err != nil || service.Store.Kind() != "memory" || service.Audit != nil {
+		t.Fatalf("expect the memory store in test, got %+v, %v", service, err)
+	}
+	if _, err = vial.GetFromContainer[*PostgresOrderStore](ctr); err == nil {
+		t.Fatalf("expect the postgres store dropped in test")
+	}
+
+	t.Setenv("VIAL_PROFILES", "prod, eu")
+	ctr = newProfileContainer()
+	ctr.Done()
+	service, err = vial.GetFromContainer[OrderService](ctr)
+	if err != nil || service.Store.Kind() != "postgres" || service.Audit == nil {
+		t.Fatalf("expect the postgres store in prod, got %+v, %v", service, err)
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	// no binding is active without profiles
+	var missing *vial.MissingBindingError
+	if err := newProfileContainer().DoneE(); !errors.As(err, &missing) {
+		t.Fatalf("expect missing binding, got %v", err)
+	}
+
+	var duplicate *vial.DuplicateRegistrationError
+	if err := newProfileContainer("dev", "prod").DoneE(); !errors.As(err, &duplicate) || !duplicate.Interface {
+		t.Fatalf("expect duplicate binding when both profiles are active, got %v", err)
+	}
+
+	var invalid *vial.InvalidDefinitionError
+	err := vial.TryBindToContainer[OrderStore, *MemoryOrderStore](vial.NewContainer(), vial.WithName("store"))
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expect only conditions allowed in Bind, got %v", err)
+	}
+}
+
+func TestDoneAgainAfterFailure(t *testing.T) {
+	ctr := newProfileContainer()
+	var missing *vial.MissingBindingError
+	if err := ctr.DoneE(); !errors.As(err, &missing) {
+		t.Fatalf("expect the store missing without profiles, got %v", err)
+	}
+	ctr.ActivateProfiles("test")
+	if err := ctr.DoneE(); err != nil {
+		t.Fatalf("expect the conditional registrations kept by the failed Done, got %v", err)
+	}
+	if service, err := vial.GetFromContainer[OrderService](ctr); err != nil || service.Store.Kind() != "memory" {
+		t.Fatalf("expect the memory store, got %+v, %v", service, err)
+	}
+}
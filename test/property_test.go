@@ -0,0 +1,120 @@
+package test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+type DatabaseConfig struct {
+	Host    string  `value:"${db.host:localhost}"`
+	Port    int     `value:"${db.port:5432}"`
+	Debug   *bool   `value:"${debug:false}"`
+	URL     string  `value:"postgres://${db.host:localhost}:${db.port:5432}/${db.name:${app.name:vial}}"`
+	Tags    string  `value:"${tags}"`
+	Timeout float64 `value:"1.5"`
+}
+
+func TestPropertyPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "app.json")
+	os.WriteFile(jsonFile, []byte(`{"db": {"host": "json-host", "port": 3306}, "tags": ["a", "b"]}`), 0o644)
+	envFile := filepath.Join(dir, ".env")
+	os.WriteFile(envFile, []byte("# local\nexport DB_PORT=6543\nDB_NAME=\"orders\"\n"), 0o644)
+	jsonSource, err := vial.NewJSONSource(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dotEnvSource, err := vial.NewDotEnvSource(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags.Bool("debug", false, "")
+	flags.String("db.host", "flag-default", "")
+	flags.Parse([]string{"-debug"})
+	t.Setenv("VIAL_TEST_DB_HOST", "env-host")
+
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[DatabaseConfig](ctr, vial.WithProtoType())
+	ctr.SetPropertySources(vial.NewFlagSource(flags), vial.NewEnvSource("VIAL_TEST_"), dotEnvSource)
+	ctr.AddPropertySource(jsonSource)
+	ctr.Done()
+
+	config, err := vial.GetFromContainer[DatabaseConfig](ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "env-host" || config.Port != 6543 || !*config.Debug || config.Tags != "a,b" || config.Timeout != 1.5 {
+		t.Fatalf("unexpected properties %+v", config)
+	}
+	if config.URL != "postgres://env-host:6543/orders" {
+		t.Fatalf("unexpected url %v", config.URL)
+	}
+}
+
+func TestPropertyExactKeys(t *testing.T) {
+	type Exact struct {
+		Host string `value:"${db.host:localhost}"`
+	}
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[Exact](ctr)
+	ctr.AddPropertySource(vial.NewMapSource("test", map[string]string{"DB_HOST": "env-style"}))
+	ctr.Done()
+	if exact, _ := vial.GetFromContainer[Exact](ctr); exact.Host != "localhost" {
+		t.Fatalf("expect only the env sources to match DB_HOST, got %v", exact.Host)
+	}
+}
+
+func TestPropertyDefaults(t *testing.T) {
+	type Defaults struct {
+		Host string `value:"${db.host:localhost}"`
+		Port *int   `value:"${db.port:5432}"`
+		Name string `value:"${db.name:${app.name:vial}}"`
+	}
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[Defaults](ctr, vial.WithProtoType())
+	ctr.AddPropertySource(vial.NewMapSource("test", map[string]string{"app.name": "shop"}))
+	ctr.Done()
+
+	defaults, err := vial.GetFromContainer[Defaults](ctr)
+	if err != nil || defaults.Host != "localhost" || *defaults.Port != 5432 || defaults.Name != "shop" {
+		t.Fatalf("expect the defaults used, got %+v, %v", defaults, err)
+	}
+}
+
+func TestPropertyErrors(t *testing.T) {
+	type Broken struct {
+		Port  int    `value:"${db.port}"`
+		Retry int    `value:"${retry:many}"`
+		Host  string `value:"${db.host}"`
+	}
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[Broken](ctr)
+	ctr.AddPropertySource(vial.NewMapSource("test", map[string]string{"db.port": "abc"}))
+	err := ctr.DoneE()
+
+	var missing *vial.MissingPropertyError
+	if !errors.As(err, &missing) || missing.Key != "db.host" {
+		t.Fatalf("expect missing property db.host, got %v", err)
+	}
+	var invalid *vial.InvalidDefinitionError
+	if !errors.As(err, &invalid) || len(err.(*vial.MultiError).Errors) != 3 {
+		t.Fatalf("expect the invalid values reported together, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `parsing "abc"`) || !strings.Contains(err.Error(), `parsing "many"`) {
+		t.Fatalf("expect parse errors of the resolved values, got %v", err)
+	}
+
+	type Unclosed struct {
+		Port int `value:"${db.port:5432"`
+	}
+	if err = vial.TryRegisterStructToContainer[Unclosed](vial.NewContainer()); !errors.As(err, &invalid) {
+		t.Fatalf("expect invalid definition for unclosed placeholder, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type ParentService struct {
+	Child vial.Lazy[*ChildService] `auto_wire:""`
+}
+
+type ChildService struct {
+	Parent *ParentService `auto_wire:""`
+}
+
+var reportCounter int
+
+type Report struct {
+	ID int
+}
+
+func NewReport() *Report {
+	reportCounter++
+	return &Report{ID: reportCounter}
+}
+
+type ReportService struct {
+	reports vial.Provider[*Report]
+}
+
+func NewReportService(reports vial.Provider[*Report]) *ReportService {
+	return &ReportService{reports: reports}
+}
+
+func TestLazyBreaksCycle(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*ParentService](ctr, vial.WithProtoType())
+	vial.RegisterStructToContainer[*ChildService](ctr, vial.WithProtoType())
+	if err := ctr.DoneE(); err != nil {
+		t.Fatal(err)
+	}
+	parent, err := vial.GetFromContainer[*ParentService](ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := parent.Child.Get()
+	if err != nil || child.Parent == nil {
+		t.Fatalf("expect lazy child resolved, got %v, %v", child, err)
+	}
+	again, _ := parent.Child.Get()
+	if again != child {
+		t.Fatalf("expect lazy to keep the first result")
+	}
+}
+
+func TestProviderOnDemand(t *testing.T) {
+	reportCounter = 0
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(NewReport, vial.WithProtoType())
+	ctr.RegisterConstructor(NewReportService, vial.WithProtoType())
+	ctr.Done()
+
+	service, _ := vial.GetFromContainer[*ReportService](ctr)
+	if reportCounter != 0 {
+		t.Fatalf("expect report not created before Get")
+	}
+	first, _ := service.reports.Get()
+	second, _ := service.reports.Get()
+	if first.ID != 1 || second.ID != 2 {
+		t.Fatalf("expect new report for each Get, got %v, %v", first.ID, second.ID)
+	}
+}
+
+type LoopParent struct {
+	Child vial.Lazy[*LoopChild] `auto_wire:""`
+}
+
+func (p *LoopParent) Init() error {
+	_, err := p.Child.Get()
+	return err
+}
+
+type LoopChild struct {
+	Parent *LoopParent `auto_wire:""`
+}
+
+func TestLazySingletonCycleInInit(t *testing.T) {
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*LoopParent](ctr)
+	vial.RegisterStructToContainer[*LoopChild](ctr)
+	ctr.Done()
+	var cycle *vial.CycleError
+	if _, err := vial.GetFromContainer[*LoopParent](ctr); !errors.As(err, &cycle) || len(cycle.Path) != 3 {
+		t.Fatalf("expect CycleError instead of waiting for itself, got %v", err)
+	}
+}
+
+var destroyed []string
+
+type HeldParent struct {
+	Child vial.Lazy[*HeldChild] `auto_wire:""`
+}
+
+func (p *HeldParent) Destroy() error {
+	destroyed = append(destroyed, "parent")
+	return nil
+}
+
+type HeldChild struct {
+	Parent *HeldParent `auto_wire:""`
+}
+
+func (c *HeldChild) Destroy() error {
+	destroyed = append(destroyed, "child")
+	return nil
+}
+
+type LazyHolder struct {
+	Target vial.Lazy[*LazyTarget] `auto_wire:""`
+}
+
+func (h *LazyHolder) Destroy() error {
+	destroyed = append(destroyed, "holder")
+	return nil
+}
+
+type LazyTarget struct{}
+
+func (l *LazyTarget) Destroy() error {
+	destroyed = append(destroyed, "target")
+	return nil
+}
+
+func TestCloseLazyDependencies(t *testing.T) {
+	destroyed = nil
+	ctr := vial.NewContainer()
+	vial.RegisterStructToContainer[*HeldParent](ctr)
+	vial.RegisterStructToContainer[*HeldChild](ctr)
+	vial.RegisterStructToContainer[*LazyHolder](ctr)
+	vial.RegisterStructToContainer[*LazyTarget](ctr)
+	ctr.Done()
+	parent, _ := vial.GetFromContainer[*HeldParent](ctr)
+	_, _ = parent.Child.Get()
+	holder, _ := vial.GetFromContainer[*LazyHolder](ctr)
+	_, _ = holder.Target.Get()
+
+	if err := ctr.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	position := map[string]int{}
+	for i, each := range destroyed {
+		position[each] = i
+	}
+	if len(destroyed) != 4 || position["child"] > position["parent"] || position["holder"] > position["target"] {
+		t.Fatalf("expect the holders to be destroyed first, got %v", destroyed)
+	}
+}
@@ -0,0 +1,233 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/GarrickZ2/vial"
+	"testing"
+)
+
+type RequestTx struct {
+	ID       int
+	Released bool
+}
+
+func (r *RequestTx) Close() error {
+	r.Released = true
+	return nil
+}
+
+var txCounter int
+
+func NewRequestTx() *RequestTx {
+	txCounter++
+	return &RequestTx{ID: txCounter}
+}
+
+type OrderHandler struct {
+	Tx *RequestTx `auto_wire:""`
+}
+
+type UserHandler struct {
+	Tx *RequestTx `auto_wire:""`
+}
+
+type SingletonHandler struct {
+	Tx *RequestTx `auto_wire:""`
+}
+
+func TestRequestScope(t *testing.T) {
+	txCounter = 0
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(NewRequestTx, vial.WithRequestScope())
+	vial.RegisterStructToContainer[OrderHandler](ctr, vial.WithProtoType())
+	vial.RegisterStructToContainer[UserHandler](ctr, vial.WithProtoType())
+	ctr.Done()
+
+	if _, err := vial.GetFromContainer[OrderHandler](ctr); err == nil {
+		t.Fatalf("expect error without request scope")
+	}
+
+	ctx, scope := ctr.NewScope(context.Background())
+	order, err := vial.GetFromContainerCtx[OrderHandler](ctx, ctr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ := vial.GetFromContainerCtx[UserHandler](ctx, ctr)
+	if order.Tx != user.Tx {
+		t.Fatalf("expect one instance in one scope")
+	}
+	if err = scope.Close(); err != nil || !order.Tx.Released {
+		t.Fatalf("expect tx released when scope closed, got %v", err)
+	}
+
+	ctx2, scope2 := ctr.NewScope(context.Background())
+	defer scope2.Close()
+	order2, _ := vial.GetFromContainerCtx[OrderHandler](ctx2, ctr)
+	if order2.Tx.ID == order.Tx.ID {
+		t.Fatalf("expect new instance in new scope")
+	}
+	if _, err = vial.GetFromContainerCtx[OrderHandler](ctx, ctr); err == nil {
+		t.Fatalf("expect error with closed scope")
+	}
+}
+
+func TestSingletonOnRequestScope(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(NewRequestTx, vial.WithRequestScope())
+	vial.RegisterStructToContainer[SingletonHandler](ctr)
+	var scopeErr *vial.ScopeError
+	if err := ctr.DoneE(); !errors.As(err, &scopeErr) {
+		t.Fatalf("expect ScopeError, got %v", err)
+	}
+}
+
+type SlowSession struct {
+	Released bool
+}
+
+func (s *SlowSession) Close() error {
+	s.Released = true
+	return nil
+}
+
+func TestCloseDuringScopedBuild(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var session *SlowSession
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(func() *SlowSession {
+		close(started)
+		<-release
+		session = &SlowSession{}
+		return session
+	}, vial.WithRequestScope())
+	ctr.Done()
+
+	ctx, scope := ctr.NewScope(context.Background())
+	result := make(chan error)
+	go func() {
+		_, err := vial.GetFromContainerCtx[*SlowSession](ctx, ctr)
+		result <- err
+	}()
+	<-started
+	if err := scope.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-result; err == nil || !session.Released {
+		t.Fatalf("expect the bean built after Close to be released, got %v", err)
+	}
+}
+
+type tenantKey struct{}
+
+type TenantScope struct {
+	beans map[string]interface{}
+}
+
+func (s *TenantScope) Get(ctx context.Context, name string, build func() (interface{}, error)) (interface{}, error) {
+	key := ctx.Value(tenantKey{}).(string) + "/" + name
+	if bean, ok := s.beans[key]; ok {
+		return bean, nil
+	}
+	bean, err := build()
+	if err == nil {
+		s.beans[key] = bean
+	}
+	return bean, err
+}
+
+type TenantCache struct {
+	Entries map[string]string
+}
+
+func NewTenantCache() *TenantCache {
+	return &TenantCache{Entries: map[string]string{}}
+}
+
+func TestCustomScope(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterScope("tenant", &TenantScope{beans: map[string]interface{}{}})
+	ctr.RegisterConstructor(NewTenantCache, vial.WithScope("tenant"))
+	ctr.Done()
+
+	ctxA := context.WithValue(context.Background(), tenantKey{}, "a")
+	ctxB := context.WithValue(context.Background(), tenantKey{}, "b")
+	a1, _ := vial.GetFromContainerCtx[*TenantCache](ctxA, ctr)
+	a2, _ := vial.GetFromContainerCtx[*TenantCache](ctxA, ctr)
+	b, _ := vial.GetFromContainerCtx[*TenantCache](ctxB, ctr)
+	if a1 != a2 || a1 == b {
+		t.Fatalf("expect one instance per tenant")
+	}
+}
+
+func TestUnknownScope(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(NewTenantCache, vial.WithScope("job"))
+	var invalid *vial.InvalidDefinitionError
+	if err := ctr.DoneE(); !errors.As(err, &invalid) {
+		t.Fatalf("expect unregistered scope error, got %v", err)
+	}
+}
+
+type TenantReport struct {
+	Cache *TenantCache `auto_wire:""`
+}
+
+func TestSingletonOnCustomScope(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterScope("tenant", &TenantScope{beans: map[string]interface{}{}})
+	ctr.RegisterConstructor(NewTenantCache, vial.WithScope("tenant"))
+	vial.RegisterStructToContainer[TenantReport](ctr)
+	var scopeErr *vial.ScopeError
+	if err := ctr.DoneE(); !errors.As(err, &scopeErr) || scopeErr.Scope != "tenant" {
+		t.Fatalf("expect ScopeError, got %v", err)
+	}
+}
+
+type OrderReport struct {
+	Handler OrderHandler `auto_wire:""`
+}
+
+func TestChildRequestScope(t *testing.T) {
+	parent := vial.NewContainer()
+	parent.RegisterConstructor(NewRequestTx, vial.WithRequestScope())
+	parent.Done()
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[OrderHandler](child, vial.WithProtoType())
+	child.Done()
+
+	ctx, scope := child.NewScope(context.Background())
+	order, err := vial.GetFromContainerCtx[OrderHandler](ctx, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := vial.GetFromContainerCtx[*RequestTx](ctx, child)
+	if err != nil || tx != order.Tx {
+		t.Fatalf("expect the parent bean kept in the scope of the child, got %v", err)
+	}
+	if err = scope.Close(); err != nil || !tx.Released {
+		t.Fatalf("expect the parent bean released with the scope, got %v", err)
+	}
+}
+
+func TestChildSingletonOnParentRequestScope(t *testing.T) {
+	parent := vial.NewContainer()
+	parent.RegisterConstructor(NewRequestTx, vial.WithRequestScope())
+	vial.RegisterStructToContainer[OrderHandler](parent, vial.WithProtoType())
+	parent.Done()
+
+	var scopeErr *vial.ScopeError
+	child := parent.NewChild()
+	vial.RegisterStructToContainer[SingletonHandler](child)
+	if err := child.DoneE(); !errors.As(err, &scopeErr) {
+		t.Fatalf("expect ScopeError for the parent request scoped bean, got %v", err)
+	}
+
+	// the prototype of the parent is built together with the singleton, so its request scoped bean counts as well
+	child = parent.NewChild()
+	vial.RegisterStructToContainer[OrderReport](child)
+	if err := child.DoneE(); !errors.As(err, &scopeErr) || scopeErr.Dependency != "*github.com/GarrickZ2/vial/test.RequestTx" {
+		t.Fatalf("expect ScopeError through the parent prototype, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+func TestSnapshotRestoreAfterOverride(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	ctr.Done()
+	origin, _ := vial.GetFromContainer[*StripeGateway](ctr)
+	snapshot := ctr.Snapshot()
+
+	vial.OverrideInContainer[PaymentGateway](ctr, &FakeGateway{})
+	if checkout, _ := vial.GetFromContainer[CheckoutService](ctr); checkout.Gateway.Charge(1) != "fake" {
+		t.Fatalf("expect the fake gateway before restore")
+	}
+
+	ctr.Restore(snapshot)
+	checkout, err := vial.GetFromContainer[CheckoutService](ctr)
+	if err != nil || checkout.Gateway != PaymentGateway(origin) {
+		t.Fatalf("expect the singleton created before the snapshot restored, got %+v, %v", checkout, err)
+	}
+	// the snapshot can be restored again after the container is changed, the gateway is kept by the override
+	vial.OverrideInContainer[PaymentGateway](ctr, &FakeGateway{})
+	ctr.Restore(snapshot)
+	if gateway, _ := vial.GetFromContainer[*StripeGateway](ctr); gateway != origin {
+		t.Fatalf("expect the snapshot restored twice, got %+v", gateway)
+	}
+}
+
+func TestSnapshotDropsLaterSingletons(t *testing.T) {
+	ctr := vial.NewContainer()
+	registerPayment(ctr)
+	ctr.Done()
+	snapshot := ctr.Snapshot()
+	first, _ := vial.GetFromContainer[*StripeGateway](ctr)
+	ctr.Restore(snapshot)
+	second, _ := vial.GetFromContainer[*StripeGateway](ctr)
+	if first == second {
+		t.Fatalf("expect the singleton created after the snapshot dropped")
+	}
+}
+
+func TestSnapshotBranchBeforeDone(t *testing.T) {
+	base := vial.NewContainer()
+	registerPayment(base)
+	snapshot := base.Snapshot()
+
+	branch := vial.NewContainer()
+	branch.Restore(snapshot)
+	vial.OverrideInContainer[PaymentGateway](branch, &FakeGateway{})
+	vial.RegisterStructToContainer[*ClosableGateway](branch)
+	branch.Done()
+	if checkout, _ := vial.GetFromContainer[CheckoutService](branch); checkout.Gateway.Charge(1) != "fake" {
+		t.Fatalf("expect the branch wired by its own registrations")
+	}
+
+	base.Done()
+	if checkout, _ := vial.GetFromContainer[CheckoutService](base); checkout.Gateway.Charge(1) != "stripe" {
+		t.Fatalf("expect the base not changed by the branch")
+	}
+	if _, err := vial.GetFromContainer[*ClosableGateway](base); err == nil {
+		t.Fatalf("expect the registration of the branch not in the base")
+	}
+}
+
+func TestCloseAfterRestore(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(func() *ClosableGateway { return &ClosableGateway{} })
+	ctr.Done()
+	origin, _ := vial.GetFromContainer[*ClosableGateway](ctr)
+	snapshot := ctr.Snapshot()
+
+	other := vial.NewContainer()
+	other.Restore(snapshot)
+	if gateway, _ := vial.GetFromContainer[*ClosableGateway](other); gateway == origin {
+		t.Fatalf("expect another container to build its own singleton")
+	}
+	if err := other.Close(context.Background()); err != nil || origin.closed {
+		t.Fatalf("expect the singleton of the source kept, got %v", err)
+	}
+
+	ctr.Restore(snapshot)
+	if gateway, _ := vial.GetFromContainer[*ClosableGateway](ctr); gateway != origin {
+		t.Fatalf("expect the singleton restored to the source")
+	}
+	if err := ctr.Close(context.Background()); err != nil || !origin.closed {
+		t.Fatalf("expect the restored singleton destroyed, got %v", err)
+	}
+	origin.closed = false
+	ctr.Restore(snapshot)
+	if err := ctr.Close(context.Background()); err != nil || origin.closed {
+		t.Fatalf("expect the destroyed singleton not restored, got %v", err)
+	}
+}
+
+func TestRestoreAfterClose(t *testing.T) {
+	ctr := vial.NewContainer()
+	ctr.RegisterConstructor(func() *ClosableGateway { return &ClosableGateway{} })
+	ctr.Done()
+	snapshot := ctr.Snapshot()
+
+	later, _ := vial.GetFromContainer[*ClosableGateway](ctr)
+	ctr.Restore(snapshot)
+	if !later.closed {
+		t.Fatalf("expect the singleton created after the snapshot destroyed by Restore")
+	}
+
+	// build once and reset for every test, the container is closed at the end of each test
+	for i := 0; i < 2; i++ {
+		gateway, err := vial.GetFromContainer[*ClosableGateway](ctr)
+		if err != nil || gateway.closed {
+			t.Fatalf("expect the restored container usable after Close, got %v", err)
+		}
+		if err = ctr.Close(context.Background()); err != nil || !gateway.closed {
+			t.Fatalf("expect the singleton destroyed by Close, got %v", err)
+		}
+		ctr.Restore(snapshot)
+	}
+}
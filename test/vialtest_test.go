@@ -0,0 +1,100 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+	"github.com/GarrickZ2/vial/vialtest"
+)
+
+// failureRecorder stops the setup at Fatalf like testing.T does, and keeps the cleanups
+type failureRecorder struct {
+	testing.TB
+	failure  string
+	cleanups []func()
+}
+
+func (f *failureRecorder) Helper() {}
+
+func (f *failureRecorder) Fatalf(format string, args ...interface{}) {
+	f.failure = fmt.Sprintf(format, args...)
+	panic(f)
+}
+
+func (f *failureRecorder) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *failureRecorder) run(fn func()) {
+	defer func() {
+		if r := recover(); r != nil && r != f {
+			panic(r)
+		}
+	}()
+	fn()
+}
+
+type ClosableGateway struct {
+	FakeGateway
+	closed bool
+}
+
+func (c *ClosableGateway) Destroy() error {
+	c.closed = true
+	return nil
+}
+
+func TestVialtestParallel(t *testing.T) {
+	for _, region := range []string{"us", "eu"} {
+		region := region
+		t.Run(region, func(t *testing.T) {
+			t.Parallel()
+			ctr := vialtest.New(t, registerPayment, func(ctr *vial.Container) {
+				vial.OverrideInContainer[*StripeGateway](ctr, &StripeGateway{Region: region})
+			})
+			checkout := vialtest.Get[CheckoutService](t, ctr)
+			if checkout.Gateway.(*StripeGateway).Region != region {
+				t.Fatalf("expect the gateway of %v, got %+v", region, checkout.Gateway)
+			}
+		})
+	}
+}
+
+func TestVialtestFailure(t *testing.T) {
+	recorder := &failureRecorder{TB: t}
+	recorder.run(func() {
+		vialtest.New(recorder, func(ctr *vial.Container) {
+			vial.RegisterStructToContainer[*FakeGateway](ctr)
+			vial.RegisterStructToContainer[*FakeGateway](ctr)
+		})
+	})
+	if !strings.Contains(recorder.failure, "vialtest: setup failed") {
+		t.Fatalf("expect the duplicate registration reported, got %q", recorder.failure)
+	}
+
+	recorder = &failureRecorder{TB: t}
+	recorder.run(func() {
+		vialtest.New(recorder, func(ctr *vial.Container) {
+			vial.RegisterStructToContainer[CheckoutService](ctr)
+		})
+	})
+	if !strings.Contains(recorder.failure, "vialtest: wiring failed") || len(recorder.cleanups) != 0 {
+		t.Fatalf("expect the missing binding reported, got %q", recorder.failure)
+	}
+}
+
+func TestVialtestCleanup(t *testing.T) {
+	recorder := &failureRecorder{TB: t}
+	ctr := vialtest.New(recorder, func(ctr *vial.Container) {
+		vial.RegisterStructToContainer[*ClosableGateway](ctr)
+	})
+	gateway := vialtest.Get[*ClosableGateway](t, ctr)
+	for _, cleanup := range recorder.cleanups {
+		cleanup()
+	}
+	if !gateway.closed {
+		t.Fatalf("expect the container closed in the cleanup")
+	}
+}
@@ -1,10 +1,9 @@
 package vial
 
 import (
-	"container/list"
 	"fmt"
 	"reflect"
-	"strconv"
+	"sort"
 	"strings"
 )
 
@@ -27,132 +26,47 @@ func getQualifiedClassName(data reflect.Type) string {
 	return builder.String()
 }
 
-func printCycleInjectionLoop(name string, l *list.List) string {
-	e := l.Front()
-	if e == nil {
+// shortName removes the package path from the qualified class name, the pointer marks are kept
+func shortName(name string) string {
+	stars := len(name) - len(strings.TrimLeft(name, "*"))
+	return name[:stars] + name[strings.LastIndex(name, ".")+1:]
+}
+
+func printCycleInjectionLoop(path []string) string {
+	if len(path) < 2 {
 		return ""
 	}
 	var msg strings.Builder
 	msg.WriteString("Cycle Injection Found in Vial:\n")
-	for e != nil {
-		msg.WriteString(fmt.Sprintf("[%v] rely on [%v]\n", name, e.Value.(string)))
-		name = e.Value.(string)
-		e = e.Next()
+	for i := 1; i < len(path); i++ {
+		msg.WriteString(fmt.Sprintf("[%v] rely on [%v]\n", path[i-1], path[i]))
 	}
 	return msg.String()
 }
 
-func validateDefaultValue(fieldType reflect.Type, value string) (val reflect.Value, errL error) {
-	if fieldType.PkgPath() != "" {
-		return reflect.Value{}, fmt.Errorf("value inject can only support original data type, won't support %v",
-			getQualifiedClassName(fieldType))
+func sortedKeys[V any](data map[string]V) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
 	}
-	concreteType, _ := getConcreteType(fieldType)
-	kind := concreteType.Kind()
-	defer func() {
-		if errL == nil {
-			val = newValue(fieldType, val)
-		}
-	}()
-	switch kind {
-	case reflect.String:
-		return reflect.ValueOf(value), nil
-	case reflect.Int:
-		res, err := strconv.ParseInt(value, 10, 32)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(int(res)), nil
-	case reflect.Int8:
-		res, err := strconv.ParseInt(value, 10, 8)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(int8(res)), nil
-	case reflect.Int16:
-		res, err := strconv.ParseInt(value, 10, 16)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(int16(res)), nil
-	case reflect.Int32:
-		res, err := strconv.ParseInt(value, 10, 32)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(int32(res)), nil
-	case reflect.Int64:
-		res, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(res), nil
-	case reflect.Uint:
-		res, err := strconv.ParseUint(value, 10, 32)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(uint(res)), nil
-	case reflect.Uint8:
-		res, err := strconv.ParseUint(value, 10, 8)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(uint8(res)), nil
-	case reflect.Uint16:
-		res, err := strconv.ParseUint(value, 10, 16)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(uint16(res)), nil
-	case reflect.Uint32:
-		res, err := strconv.ParseUint(value, 10, 32)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(uint32(res)), nil
-	case reflect.Uint64:
-		res, err := strconv.ParseUint(value, 10, 64)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(res), nil
-	case reflect.Bool:
-		res, err := strconv.ParseBool(value)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(res), nil
-	case reflect.Float32:
-		res, err := strconv.ParseFloat(value, 32)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(float32(res)), nil
-	case reflect.Float64:
-		res, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(res), nil
-	case reflect.Complex64:
-		res, err := strconv.ParseComplex(value, 64)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(complex64(res)), nil
-	case reflect.Complex128:
-		res, err := strconv.ParseComplex(value, 128)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		return reflect.ValueOf(res), nil
-	default:
-		return reflect.Value{}, fmt.Errorf("data type %v cannot use value tag", kind)
+	sort.Strings(keys)
+	return keys
+}
+
+func formatDependencyPath(path []string) string {
+	if len(path) == 0 {
+		return ""
 	}
+	return " (dependency path: " + strings.Join(path, " -> ") + ")"
 }
 
 func getKindType(dataType reflect.Type) kindType {
+	if dataType.Kind() == reflect.Slice && dataType.Elem().Kind() == reflect.Interface {
+		return interfaceSliceKind
+	}
+	if dataType.Kind() == reflect.Map && dataType.Key().Kind() == reflect.String && dataType.Elem().Kind() == reflect.Interface {
+		return interfaceMapKind
+	}
 	dataType, _ = getConcreteType(dataType)
 	switch dataType.Kind() {
 	case reflect.Interface:
@@ -162,16 +76,6 @@ func getKindType(dataType reflect.Type) kindType {
 	}
 }
 
-func newValue(targetType reflect.Type, injectValue reflect.Value) reflect.Value {
-	if targetType.Kind() == reflect.Pointer {
-		valPtr := reflect.New(targetType.Elem())
-		elem := newValue(targetType.Elem(), injectValue)
-		valPtr.Elem().Set(elem)
-		return valPtr
-	}
-	return injectValue
-}
-
 func newValueByInject(targetType reflect.Type, injectValues []reflect.Value) reflect.Value {
 	if targetType.Kind() == reflect.Pointer {
 		valPtr := reflect.New(targetType.Elem())
@@ -183,12 +87,69 @@ func newValueByInject(targetType reflect.Type, injectValues []reflect.Value) ref
 	elem := reflect.New(targetType).Elem()
 	for i := 0; i < targetType.NumField(); i++ {
 		field := targetType.Field(i)
-		_, exist1 := field.Tag.Lookup(autoWire)
-		_, exist2 := field.Tag.Lookup(value)
-		if exist1 || exist2 {
+		if isInjectedField(field) {
 			elem.Field(i).Set(injectValues[ptr])
 			ptr++
 		}
 	}
 	return elem
 }
+
+func isInjectedField(field reflect.StructField) bool {
+	for _, tag := range []string{autoWire, value, optional} {
+		if _, exist := field.Tag.Lookup(tag); exist {
+			return true
+		}
+	}
+	return false
+}
+
+// deepCopy copies the pointers, slices and maps in the value, so the copy can be changed without touching the
+// origin. The unexported fields and the interfaces are still shared
+func deepCopy(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.New(value.Type().Elem())
+		result.Elem().Set(deepCopy(value.Elem()))
+		return result
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			result.Index(i).Set(deepCopy(value.Index(i)))
+		}
+		return result
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return result
+	case reflect.Array, reflect.Struct:
+		result := reflect.New(value.Type()).Elem()
+		result.Set(value)
+		if value.Kind() == reflect.Array {
+			for i := 0; i < value.Len(); i++ {
+				result.Index(i).Set(deepCopy(value.Index(i)))
+			}
+			return result
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if result.Field(i).CanSet() {
+				result.Field(i).Set(deepCopy(value.Field(i)))
+			}
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -1,5 +1,10 @@
 package vial
 
+import (
+	"context"
+	"reflect"
+)
+
 func RegisterStruct[T any](options ...applyOption) {
 	RegisterStructToContainer[T](c, options...)
 }
@@ -9,14 +14,39 @@ func RegisterStructToContainer[T any](ctr *Container, options ...applyOption) {
 	ctr.RegisterStructByInstance(structType, options...)
 }
 
+func TryRegisterStruct[T any](options ...applyOption) error {
+	return TryRegisterStructToContainer[T](c, options...)
+}
+
+func TryRegisterStructToContainer[T any](ctr *Container, options ...applyOption) error {
+	var structType T
+	return ctr.TryRegisterStructByInstance(structType, options...)
+}
+
 func RegisterStructByInstance(structType interface{}, options ...applyOption) {
 	c.RegisterStructByInstance(structType, options...)
 }
 
+func TryRegisterStructByInstance(structType interface{}, options ...applyOption) error {
+	return c.TryRegisterStructByInstance(structType, options...)
+}
+
 func RegisterConstructor(constructor interface{}, options ...applyOption) {
 	c.RegisterConstructor(constructor, options...)
 }
 
+func TryRegisterConstructor(constructor interface{}, options ...applyOption) error {
+	return c.TryRegisterConstructor(constructor, options...)
+}
+
+func RegisterInstance(instance interface{}, options ...applyOption) {
+	c.RegisterValue(instance, options...)
+}
+
+func TryRegisterInstance(instance interface{}, options ...applyOption) error {
+	return c.TryRegisterValue(instance, options...)
+}
+
 func Bind[T any, P any](others ...interface{}) {
 	BindToContainer[T, P](c, others...)
 }
@@ -27,10 +57,24 @@ func BindToContainer[T any, P any](ctr *Container, others ...interface{}) {
 	ctr.Bind(i, primaryStruct, others...)
 }
 
+func TryBind[T any, P any](others ...interface{}) error {
+	return TryBindToContainer[T, P](c, others...)
+}
+
+func TryBindToContainer[T any, P any](ctr *Container, others ...interface{}) error {
+	i := new(T)
+	var primaryStruct P
+	return ctr.TryBind(i, primaryStruct, others...)
+}
+
 func BindByInstance(i interface{}, primaryStruct interface{}, others ...interface{}) {
 	c.Bind(i, primaryStruct, others...)
 }
 
+func TryBindByInstance(i interface{}, primaryStruct interface{}, others ...interface{}) error {
+	return c.TryBind(i, primaryStruct, others...)
+}
+
 func GetByInstance(dataType interface{}) (interface{}, error) {
 	return c.GetByInstance(dataType)
 }
@@ -48,10 +92,131 @@ func GetFromContainer[T any](ctr *Container) (T, error) {
 	return value.(T), err
 }
 
+func GetNamed[T any](name string) (T, error) {
+	return GetNamedFromContainer[T](c, name)
+}
+
+func GetNamedFromContainer[T any](ctr *Container, name string) (T, error) {
+	var data T
+	value, err := ctr.GetNamedByInstance(typeTarget[T](), name)
+	if err != nil {
+		return data, err
+	}
+	return value.(T), err
+}
+
+func GetNamedByInstance(dataType interface{}, name string) (interface{}, error) {
+	return c.GetNamedByInstance(dataType, name)
+}
+
+func GetCtx[T any](ctx context.Context) (T, error) {
+	return GetFromContainerCtx[T](ctx, c)
+}
+
+func GetFromContainerCtx[T any](ctx context.Context, ctr *Container) (T, error) {
+	var data T
+	value, err := ctr.GetByInstanceCtx(ctx, data)
+	if err != nil {
+		return data, err
+	}
+	return value.(T), err
+}
+
+func GetByInstanceCtx(ctx context.Context, dataType interface{}) (interface{}, error) {
+	return c.GetByInstanceCtx(ctx, dataType)
+}
+
+func NewScope(ctx context.Context) (context.Context, *RequestScope) {
+	return c.NewScope(ctx)
+}
+
+func RegisterScope(name string, scope Scope) {
+	c.RegisterScope(name, scope)
+}
+
+func TryRegisterScope(name string, scope Scope) error {
+	return c.TryRegisterScope(name, scope)
+}
+
+func RegisterConfig[T any](prefix string, options ...applyOption) {
+	RegisterConfigToContainer[T](c, prefix, options...)
+}
+
+func RegisterConfigToContainer[T any](ctr *Container, prefix string, options ...applyOption) {
+	var config T
+	ctr.RegisterConfig(config, prefix, options...)
+}
+
+func TryRegisterConfig[T any](prefix string, options ...applyOption) error {
+	return TryRegisterConfigToContainer[T](c, prefix, options...)
+}
+
+func TryRegisterConfigToContainer[T any](ctr *Container, prefix string, options ...applyOption) error {
+	var config T
+	return ctr.TryRegisterConfig(config, prefix, options...)
+}
+
+func AddPropertySource(source PropertySource) {
+	c.AddPropertySource(source)
+}
+
+func SetPropertySources(sources ...PropertySource) {
+	c.SetPropertySources(sources...)
+}
+
+func ActivateProfiles(profiles ...string) {
+	c.ActivateProfiles(profiles...)
+}
+
+func Refresh() error {
+	return c.Refresh()
+}
+
+func Override[T any](replacement interface{}) {
+	OverrideInContainer[T](c, replacement)
+}
+
+func OverrideInContainer[T any](ctr *Container, replacement interface{}) {
+	ctr.Override(typeTarget[T](), replacement)
+}
+
+func TryOverride[T any](replacement interface{}) error {
+	return TryOverrideInContainer[T](c, replacement)
+}
+
+func TryOverrideInContainer[T any](ctr *Container, replacement interface{}) error {
+	return ctr.TryOverride(typeTarget[T](), replacement)
+}
+
+// typeTarget returns the pointer of T if T is an interface, since the zero interface has no type
+func typeTarget[T any]() interface{} {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Interface {
+		return new(T)
+	}
+	var target T
+	return target
+}
+
+func OverrideByInstance(target interface{}, replacement interface{}) {
+	c.Override(target, replacement)
+}
+
+func TryOverrideByInstance(target interface{}, replacement interface{}) error {
+	return c.TryOverride(target, replacement)
+}
+
 func Done() {
 	c.Done()
 }
 
+func DoneE() error {
+	return c.DoneE()
+}
+
+func Close(ctx context.Context) error {
+	return c.Close(ctx)
+}
+
 func NewContainer() *Container {
 	return newContainer()
 }
@@ -0,0 +1,63 @@
+// Package vialtest creates isolated containers for tests, so the tests don't share the default container
+// and can run in parallel.
+package vialtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/GarrickZ2/vial"
+)
+
+// New creates a container by the setup, e.g. the Register function of the application, then applies the overrides
+// and calls Done. The wiring errors fail the test instead of panicking, and the container is closed in the cleanup.
+func New(t testing.TB, setup func(ctr *vial.Container), overrides ...func(ctr *vial.Container)) *vial.Container {
+	t.Helper()
+	ctr := vial.NewContainer()
+	if err := apply(ctr, setup); err != nil {
+		t.Fatalf("vialtest: setup failed: %v", err)
+	}
+	for _, each := range overrides {
+		if err := apply(ctr, each); err != nil {
+			t.Fatalf("vialtest: override failed: %v", err)
+		}
+	}
+	if err := ctr.DoneE(); err != nil {
+		t.Fatalf("vialtest: wiring failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := ctr.Close(context.Background()); err != nil {
+			t.Errorf("vialtest: close failed: %v", err)
+		}
+	})
+	return ctr
+}
+
+// Get gets the bean from the container, and fails the test if it cannot be built
+func Get[T any](t testing.TB, ctr *vial.Container) T {
+	t.Helper()
+	result, err := vial.GetFromContainer[T](ctr)
+	if err != nil {
+		t.Fatalf("vialtest: get failed: %v", err)
+	}
+	return result
+}
+
+// apply calls the function, the panic of the registration is returned as an error
+func apply(ctr *vial.Container, fn func(ctr *vial.Container)) (err error) {
+	if fn == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if recovered, ok := r.(error); ok {
+				err = recovered
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	fn(ctr)
+	return nil
+}